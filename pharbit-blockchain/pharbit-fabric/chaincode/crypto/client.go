@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+)
+
+// Client builds signed payloads for a single off-chain account, tracking the
+// nonce it expects to use next so callers don't have to query the ledger
+// before every transaction.
+type Client struct {
+	Address    string
+	PublicKey  string
+	privateKey ed25519.PrivateKey
+	nextNonce  uint64
+}
+
+// NewClient creates a Client from a generated key pair, ready to sign
+// transactions starting at nonce 1.
+func NewClient() (*Client, error) {
+	_, priv, pubHex, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	address, err := AddressFromPublicKey(pubHex)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		Address:    address,
+		PublicKey:  pubHex,
+		privateKey: priv,
+		nextNonce:  1,
+	}, nil
+}
+
+// BuildSignedTransaction signs a transfer to receiver for quantity using the
+// client's next nonce and returns the JSON payload expected by
+// TransactionContract.CreateTransaction.
+func (c *Client) BuildSignedTransaction(receiver string, quantity float64) ([]byte, error) {
+	tx := SignedTransaction{
+		Sender:   c.Address,
+		Receiver: receiver,
+		Quantity: quantity,
+		Nonce:    c.nextNonce,
+	}
+	sig, err := Sign(c.privateKey, tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.Signature = sig
+
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signed transaction: %v", err)
+	}
+	c.nextNonce++
+	return payload, nil
+}
@@ -0,0 +1,120 @@
+// Package crypto provides the signing and verification primitives shared by
+// the transaction-tracking chaincode and its off-chain client. Transactions
+// are signed with ed25519; the signer's address is the hex-encoded SHA-256
+// digest of its public key, mirroring the address-from-pubkey pattern used
+// by common.BytesToAddress in go-ethereum.
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// SignedTransaction is the off-chain-signed payload accepted by
+// TransactionContract.CreateTransaction. Signature covers the canonical
+// encoding of every other field.
+type SignedTransaction struct {
+	Sender    string  `json:"sender"`
+	Receiver  string  `json:"receiver"`
+	Quantity  float64 `json:"quantity"`
+	Nonce     uint64  `json:"nonce"`
+	Signature string  `json:"signature"`
+}
+
+// canonicalFields is the deterministic, signature-free view of a
+// SignedTransaction that gets hashed and signed.
+type canonicalFields struct {
+	Sender   string  `json:"sender"`
+	Receiver string  `json:"receiver"`
+	Quantity float64 `json:"quantity"`
+	Nonce    uint64  `json:"nonce"`
+}
+
+// Canonicalize returns the deterministic JSON encoding of tx's non-signature
+// fields. Both the signer and the chaincode must hash this exact byte
+// sequence, so field order and the field set are fixed by canonicalFields.
+func Canonicalize(tx SignedTransaction) ([]byte, error) {
+	canonical, err := json.Marshal(canonicalFields{
+		Sender:   tx.Sender,
+		Receiver: tx.Receiver,
+		Quantity: tx.Quantity,
+		Nonce:    tx.Nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize transaction: %v", err)
+	}
+	return canonical, nil
+}
+
+// Hash returns the SHA-256 digest of the transaction's canonical encoding.
+func Hash(tx SignedTransaction) (string, error) {
+	canonical, err := Canonicalize(tx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return "0x" + hex.EncodeToString(sum[:]), nil
+}
+
+// GenerateKey generates a new ed25519 key pair for use by an off-chain
+// client, along with the hex-encoded public key to register on-chain.
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to generate key: %v", err)
+	}
+	return pub, priv, hex.EncodeToString(pub), nil
+}
+
+// Sign signs tx's canonical encoding with privKey and returns the
+// signature hex-encoded, ready to populate SignedTransaction.Signature.
+func Sign(privKey ed25519.PrivateKey, tx SignedTransaction) (string, error) {
+	canonical, err := Canonicalize(tx)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(privKey, canonical)
+	return hex.EncodeToString(sig), nil
+}
+
+// Verify checks that sig (hex-encoded) is a valid ed25519 signature of tx's
+// canonical encoding under pubKeyHex (hex-encoded).
+func Verify(pubKeyHex string, sig string, tx SignedTransaction) error {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid public key encoding: %v", err)
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key length: expected %d bytes, got %d", ed25519.PublicKeySize, len(pubKey))
+	}
+	sigBytes, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	canonical, err := Canonicalize(tx)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), canonical, sigBytes) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// AddressFromPublicKey derives the account address a public key registers
+// under: the hex-encoded SHA-256 digest of the raw public key bytes. This is
+// not signature recovery (ed25519 has no public-key-from-signature
+// operation the way ECDSA does) — it only ever hashes a pubkey callers
+// already hold.
+func AddressFromPublicKey(pubKeyHex string) (string, error) {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key encoding: %v", err)
+	}
+	sum := sha256.Sum256(pubKey)
+	return "0x" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,188 @@
+// Package dummychain fakes just enough of the Fabric chaincode runtime
+// (contractapi.TransactionContextInterface, shim.ChaincodeStubInterface and
+// cid.ClientIdentity) to drive a contract in-process, following
+// the vm_dummy pattern from Aergo: no Docker, no peer, no channel.
+package dummychain
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Event is one chaincode event captured during Invoke.
+type Event struct {
+	Name    string
+	Payload []byte
+}
+
+// version is one recorded write to a key, used to answer GetHistoryForKey.
+type version struct {
+	txID      string
+	value     []byte
+	timestamp time.Time
+}
+
+// Chain is an in-process, single-org fake of a Fabric channel's world
+// state. It supports advancing a synthetic block clock and impersonating a
+// different client identity for each Invoke call.
+type Chain struct {
+	data    map[string][]byte
+	history map[string][]version
+	events  []Event
+	clock   *Clock
+
+	identity string
+	mspID    string
+
+	currentTxID string
+	txCounter   int
+}
+
+// NewChain creates an empty chain with its clock started at a fixed,
+// deterministic instant and a default identity of "Org1MSP" client "admin".
+func NewChain() *Chain {
+	return &Chain{
+		data:     make(map[string][]byte),
+		history:  make(map[string][]version),
+		clock:    NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		identity: "x509::CN=admin,OU=client::CN=ca.org1.example.com",
+		mspID:    "Org1MSP",
+	}
+}
+
+// NextBlock advances the chain's block clock, so GetTxTimestamp and the
+// synthetic GetHistoryForKey bookkeeping it feeds are deterministic and
+// ordered across successive Invoke calls. ExchangeContract's order and trade
+// timestamps come from ctx.GetStub().GetTxTimestamp() and so track this
+// clock. TransactionContract's and MedicineTrackingContract's
+// CreatedAt/UpdatedAt/Timestamp fields are wall-clock reads via time.Now()
+// and do not.
+func (c *Chain) NextBlock() {
+	c.clock.Advance(blockInterval)
+}
+
+// AsClientIdentity impersonates id (the value GetClientIdentity().GetID()
+// will return) under mspID for every Invoke call until changed again.
+func (c *Chain) AsClientIdentity(id string, mspID string) *Chain {
+	c.identity = id
+	c.mspID = mspID
+	return c
+}
+
+// Events returns every event emitted by Invoke calls so far.
+func (c *Chain) Events() []Event {
+	return append([]Event(nil), c.events...)
+}
+
+func (c *Chain) putState(key string, value []byte) {
+	c.data[key] = value
+	c.history[key] = append(c.history[key], version{
+		txID:      c.currentTxID,
+		value:     value,
+		timestamp: c.clock.Now(),
+	})
+}
+
+func (c *Chain) newContext() contractapi.TransactionContextInterface {
+	return &fakeCtx{
+		stub:     &fakeStub{chain: c},
+		identity: &fakeClientIdentity{id: c.identity, mspID: c.mspID},
+	}
+}
+
+// Invoke calls contract.Method(ctx, args...) the way Fabric would: args are
+// marshalled as strings and converted to each parameter's declared type,
+// and the final non-error return value (if any) is marshalled to JSON.
+func (c *Chain) Invoke(contract interface{}, method string, args ...string) (string, error) {
+	c.txCounter++
+	c.currentTxID = fmt.Sprintf("TX%d", c.txCounter)
+
+	fn := reflect.ValueOf(contract).MethodByName(method)
+	if !fn.IsValid() {
+		return "", fmt.Errorf("contract has no method %s", method)
+	}
+	fnType := fn.Type()
+
+	// Parameter 0 is always the transaction context.
+	wantArgs := fnType.NumIn() - 1
+	if wantArgs != len(args) {
+		return "", fmt.Errorf("%s expects %d argument(s), got %d", method, wantArgs, len(args))
+	}
+
+	in := make([]reflect.Value, fnType.NumIn())
+	in[0] = reflect.ValueOf(c.newContext())
+	for i, arg := range args {
+		paramType := fnType.In(i + 1)
+		value, err := convertArg(arg, paramType)
+		if err != nil {
+			return "", fmt.Errorf("argument %d of %s: %v", i, method, err)
+		}
+		in[i+1] = value
+	}
+
+	out := fn.Call(in)
+	return unpackResult(out)
+}
+
+// convertArg converts the string argument Fabric would have sent on the
+// wire into the Go type the contract method declares.
+func convertArg(arg string, paramType reflect.Type) (reflect.Value, error) {
+	switch paramType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(arg), nil
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("not a float64: %v", err)
+		}
+		return reflect.ValueOf(v), nil
+	case reflect.Uint64:
+		v, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("not a uint64: %v", err)
+		}
+		return reflect.ValueOf(v), nil
+	case reflect.Int32:
+		v, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("not an int32: %v", err)
+		}
+		return reflect.ValueOf(int32(v)), nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(arg)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("not a bool: %v", err)
+		}
+		return reflect.ValueOf(v), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported parameter type %s", paramType)
+	}
+}
+
+// unpackResult splits a contract method's return values into its JSON
+// payload and error, the same shape a Fabric chaincode response has.
+func unpackResult(out []reflect.Value) (string, error) {
+	if len(out) == 0 {
+		return "", nil
+	}
+
+	errValue := out[len(out)-1]
+	if !errValue.IsNil() {
+		return "", errValue.Interface().(error)
+	}
+	if len(out) == 1 {
+		return "", nil
+	}
+
+	payload := out[0].Interface()
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return string(payloadJSON), nil
+}
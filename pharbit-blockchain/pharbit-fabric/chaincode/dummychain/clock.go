@@ -0,0 +1,28 @@
+package dummychain
+
+import "time"
+
+// blockInterval is how far NextBlock advances the chain's clock, modelled
+// loosely on Fabric's default block cutting interval.
+const blockInterval = 2 * time.Second
+
+// Clock is an injectable, manually-advanced clock so state transitions that
+// depend on time.Now()-style behaviour become deterministic in tests.
+type Clock struct {
+	now time.Time
+}
+
+// NewClock creates a Clock starting at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
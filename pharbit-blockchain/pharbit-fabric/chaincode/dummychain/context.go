@@ -0,0 +1,21 @@
+package dummychain
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// fakeCtx implements contractapi.TransactionContextInterface over a single
+// fakeStub and the identity impersonated by Chain at Invoke time.
+type fakeCtx struct {
+	stub     *fakeStub
+	identity *fakeClientIdentity
+}
+
+func (f *fakeCtx) GetStub() shim.ChaincodeStubInterface {
+	return f.stub
+}
+
+func (f *fakeCtx) GetClientIdentity() cid.ClientIdentity {
+	return f.identity
+}
@@ -0,0 +1,33 @@
+package dummychain
+
+import (
+	"crypto/x509"
+	"fmt"
+)
+
+// fakeClientIdentity impersonates a single enrolled client for the
+// duration of one Invoke call, standing in for cid.ClientIdentity.
+type fakeClientIdentity struct {
+	id    string
+	mspID string
+}
+
+func (f *fakeClientIdentity) GetID() (string, error) {
+	return f.id, nil
+}
+
+func (f *fakeClientIdentity) GetMSPID() (string, error) {
+	return f.mspID, nil
+}
+
+func (f *fakeClientIdentity) GetAttributeValue(attrName string) (value string, found bool, err error) {
+	return "", false, nil
+}
+
+func (f *fakeClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	return fmt.Errorf("attribute %s is not set on this dummychain identity", attrName)
+}
+
+func (f *fakeClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, fmt.Errorf("dummychain identities are not backed by an X.509 certificate")
+}
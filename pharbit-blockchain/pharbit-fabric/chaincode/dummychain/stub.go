@@ -0,0 +1,187 @@
+package dummychain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// fakeStub implements just enough of shim.ChaincodeStubInterface to run the
+// contracts in this repo against an in-process Chain: state, range
+// queries, composite keys, history and events. Everything else is
+// inherited from the embedded nil interface and panics if a contract ever
+// calls it, which is the signal to extend this fake rather than silently
+// return zero values.
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+	chain *Chain
+}
+
+func (s *fakeStub) GetState(key string) ([]byte, error) {
+	return s.chain.data[key], nil
+}
+
+func (s *fakeStub) PutState(key string, value []byte) error {
+	s.chain.putState(key, value)
+	return nil
+}
+
+func (s *fakeStub) DelState(key string) error {
+	delete(s.chain.data, key)
+	return nil
+}
+
+func (s *fakeStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	keys := make([]string, 0, len(s.chain.data))
+	for key := range s.chain.data {
+		if startKey != "" && key < startKey {
+			continue
+		}
+		if endKey != "" && key >= endKey {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, &queryresult.KV{Key: key, Value: s.chain.data[key]})
+	}
+	return &kvIterator{results: results}, nil
+}
+
+// GetQueryResultWithPagination emulates a CouchDB rich query by evaluating
+// query's selector clause (see store.ParseSelector/store.MatchSelector)
+// against every JSON-object value in world state, in key order. It takes
+// the same query document real Fabric does, but only evaluates what
+// store.MatchSelector understands (no $and/$or), and has no actual CouchDB
+// or indexes behind it.
+func (s *fakeStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	selector, err := store.ParseSelector(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, 0, len(s.chain.data))
+	for key := range s.chain.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if bookmark != "" {
+		start = sort.SearchStrings(keys, bookmark)
+		if start < len(keys) && keys[start] == bookmark {
+			start++
+		}
+	}
+
+	var results []*queryresult.KV
+	var fetched int32
+	nextBookmark := ""
+	for _, key := range keys[start:] {
+		if pageSize > 0 && fetched >= pageSize {
+			break
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(s.chain.data[key], &doc); err != nil {
+			continue
+		}
+		if !store.MatchSelector(selector, doc) {
+			continue
+		}
+		results = append(results, &queryresult.KV{Key: key, Value: s.chain.data[key]})
+		fetched++
+		nextBookmark = key
+	}
+
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: fetched, Bookmark: nextBookmark}
+	return &kvIterator{results: results}, metadata, nil
+}
+
+func (s *fakeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	versions := s.chain.history[key]
+	modifications := make([]*queryresult.KeyModification, len(versions))
+	for i, v := range versions {
+		ts, err := ptypes.TimestampProto(v.timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert history timestamp: %v", err)
+		}
+		// Most recent first, matching the real GetHistoryForKey.
+		modifications[len(versions)-1-i] = &queryresult.KeyModification{
+			TxId:      v.txID,
+			Value:     v.value,
+			Timestamp: ts,
+			IsDelete:  false,
+		}
+	}
+	return &historyIterator{results: modifications}, nil
+}
+
+func (s *fakeStub) SetEvent(name string, payload []byte) error {
+	s.chain.events = append(s.chain.events, Event{Name: name, Payload: payload})
+	return nil
+}
+
+func (s *fakeStub) GetTxID() string {
+	return s.chain.currentTxID
+}
+
+func (s *fakeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return ptypes.TimestampProto(s.chain.clock.Now())
+}
+
+// CreateCompositeKey builds the same composite-key byte format real
+// Fabric's ChaincodeStubInterface.CreateCompositeKey does (see
+// store.CompositeKey), so FabricStore.TransactionKey/AccountKey work
+// identically whether c.store(ctx) resolves to a real stub or this fake.
+func (s *fakeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return store.CompositeKey(objectType, attributes...), nil
+}
+
+// kvIterator walks a pre-computed slice of range-query results.
+type kvIterator struct {
+	results []*queryresult.KV
+	pos     int
+}
+
+func (it *kvIterator) HasNext() bool { return it.pos < len(it.results) }
+
+func (it *kvIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	result := it.results[it.pos]
+	it.pos++
+	return result, nil
+}
+
+func (it *kvIterator) Close() error { return nil }
+
+// historyIterator walks a pre-computed slice of key modifications.
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	pos     int
+}
+
+func (it *historyIterator) HasNext() bool { return it.pos < len(it.results) }
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	result := it.results[it.pos]
+	it.pos++
+	return result, nil
+}
+
+func (it *historyIterator) Close() error { return nil }
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/settlement"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// Order sides.
+const (
+	SideBid = "BID"
+	SideAsk = "ASK"
+)
+
+// Order statuses.
+const (
+	OrderStatusOpen            = "Open"
+	OrderStatusPartiallyFilled = "PartiallyFilled"
+	OrderStatusFilled          = "Filled"
+	OrderStatusCancelled       = "Cancelled"
+)
+
+// priceScale converts a float price into a fixed-point integer so it can be
+// zero-padded into a lexicographically sortable key component.
+const priceScale = 1e8
+
+// priceInvert is added as an offset larger than any realistic scaled price,
+// so subtracting a price from it turns ascending order into descending
+// order for the BID book's price-time-priority key.
+const priceInvert = int64(1e18)
+
+// Order is a resting or filled limit order against assetPair, priced and
+// quantified in the same unit as Account.Balance (this ledger tracks a
+// single fungible balance, so PlaceOrder moves that balance between
+// counterparties rather than swapping distinct assets).
+type Order struct {
+	ID                string    `json:"id"`
+	Trader            string    `json:"trader"`
+	Side              string    `json:"side"`
+	AssetPair         string    `json:"assetPair"`
+	Price             float64   `json:"price"`
+	Quantity          float64   `json:"quantity"`
+	RemainingQuantity float64   `json:"remainingQuantity"`
+	Status            string    `json:"status"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// Trade is one matched fill between a bid and an ask order.
+type Trade struct {
+	ID         string    `json:"id"`
+	AssetPair  string    `json:"assetPair"`
+	Price      float64   `json:"price"`
+	Quantity   float64   `json:"quantity"`
+	BidOrderID string    `json:"bidOrderId"`
+	AskOrderID string    `json:"askOrderId"`
+	Buyer      string    `json:"buyer"`
+	Seller     string    `json:"seller"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// OrderBookLevel is one resting order as surfaced by GetOrderBook.
+type OrderBookLevel struct {
+	OrderID  string  `json:"orderId"`
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBook is the top of book for an asset pair, best price first on each
+// side.
+type OrderBook struct {
+	AssetPair string           `json:"assetPair"`
+	Bids      []OrderBookLevel `json:"bids"`
+	Asks      []OrderBookLevel `json:"asks"`
+}
+
+// ExchangeContract layers a limit-order matching engine on top of the
+// Account balances TransactionContract manages. Store decouples business
+// logic from ctx.GetStub() the same way TransactionContract and
+// MedicineTrackingContract do.
+type ExchangeContract struct {
+	contractapi.Contract
+	Store store.Store
+}
+
+// NewExchangeContract constructs an ExchangeContract against an explicit
+// store, so world-state reads and writes bypass ctx.GetStub(); PlaceOrder
+// still reads the transaction ID and timestamp from ctx.GetStub(). Used by
+// tests running against store.NewInMemoryStore and by the dummychain
+// harness.
+func NewExchangeContract(s store.Store) *ExchangeContract {
+	return &ExchangeContract{Store: s}
+}
+
+func (c *ExchangeContract) store(ctx contractapi.TransactionContextInterface) store.Store {
+	if c.Store != nil {
+		return c.Store
+	}
+	return store.NewFabricStore(ctx.GetStub())
+}
+
+// PlaceOrder submits a limit order for trader and immediately walks the
+// opposite side of assetPair's book best-price-first, filling against
+// resting orders at their price (price-time priority) until either the
+// order is fully matched or no more crossing orders remain. Any unfilled
+// remainder rests on the book. Each match settles by moving the matched
+// quantity's notional value (quantity * the resting order's price) of
+// Account.Balance from the buyer to the seller, re-using the same
+// settlement logic TransactionContract.CreateTransaction uses. trader must
+// be the caller's own identity and must already have a registered account;
+// otherwise anyone could place orders (and so move balances) for any
+// address, and a made-up trader could rest an order that can never settle
+// or be cancelled.
+func (c *ExchangeContract) PlaceOrder(ctx contractapi.TransactionContextInterface,
+	trader string, side string, assetPair string, price float64, quantity float64) (string, error) {
+
+	if side != SideBid && side != SideAsk {
+		return "", fmt.Errorf("side must be %q or %q, got %q", SideBid, SideAsk, side)
+	}
+	if assetPair == "" {
+		return "", fmt.Errorf("assetPair cannot be empty")
+	}
+	if price <= 0 {
+		return "", fmt.Errorf("price must be greater than 0")
+	}
+	if quantity <= 0 {
+		return "", fmt.Errorf("quantity must be greater than 0")
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if callerID != trader {
+		return "", fmt.Errorf("trader must be the caller's own identity")
+	}
+
+	exists, err := c.accountExists(ctx, trader)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("account with address %s does not exist", trader)
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return "", err
+	}
+	order := &Order{
+		ID:                fmt.Sprintf("ORDER_%s", ctx.GetStub().GetTxID()),
+		Trader:            trader,
+		Side:              side,
+		AssetPair:         assetPair,
+		Price:             price,
+		Quantity:          quantity,
+		RemainingQuantity: quantity,
+		Status:            OrderStatusOpen,
+		Timestamp:         now,
+	}
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal order: %v", err)
+	}
+	if err := c.store(ctx).EmitEvent("OrderPlaced", orderJSON); err != nil {
+		return "", err
+	}
+
+	if err := c.match(ctx, order); err != nil {
+		return "", err
+	}
+
+	if order.RemainingQuantity > 0 {
+		if order.RemainingQuantity < order.Quantity {
+			order.Status = OrderStatusPartiallyFilled
+		}
+		if err := c.store(ctx).PutBookEntry(bookKey(order.Side, order.AssetPair, order.Price, order.Timestamp, order.ID), []byte(order.ID)); err != nil {
+			return "", err
+		}
+	} else {
+		order.Status = OrderStatusFilled
+	}
+
+	orderJSON, err = json.Marshal(order)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal order: %v", err)
+	}
+	if err := c.store(ctx).PutOrder(order.ID, orderJSON); err != nil {
+		return "", err
+	}
+
+	return order.ID, nil
+}
+
+// match walks the opposite side of order's book, best price first, filling
+// order against resting orders until it is fully filled or no crossing
+// order remains. A resting order whose trader can no longer settle (e.g.
+// drained by an ordinary transfer after the order was placed, or never
+// actually registered — orders placed before this fix went in may still
+// reference a made-up address) is evicted as Cancelled and skipped rather
+// than stopping the scan, so one stale order can't block order from
+// matching against other, solvent orders at the same or worse price.
+// Every other Settle failure (a self-trade, order's own account going
+// missing) is order's own problem, not resting's, and is surfaced instead
+// of blamed on whichever side happened to initiate.
+func (c *ExchangeContract) match(ctx contractapi.TransactionContextInterface, order *Order) error {
+	startKey, endKey := oppositeBookRange(order.Side, order.AssetPair)
+	entries, err := c.store(ctx).ListBookEntriesByRange(startKey, endKey)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if order.RemainingQuantity <= 0 {
+			break
+		}
+
+		restingID := string(entry.Value)
+		resting, err := c.getOrder(ctx, restingID)
+		if err != nil {
+			return err
+		}
+		if resting.RemainingQuantity <= 0 || resting.Status == OrderStatusCancelled {
+			continue
+		}
+		if !crosses(order.Side, order.Price, resting.Price) {
+			break
+		}
+
+		if order.Trader == resting.Trader {
+			return fmt.Errorf("order %s cannot match against its own resting order %s", order.ID, resting.ID)
+		}
+
+		matchQuantity := math.Min(order.RemainingQuantity, resting.RemainingQuantity)
+		notional := matchQuantity * resting.Price
+
+		restingIsBuyer := order.Side == SideAsk
+		buyer, seller := order.Trader, resting.Trader
+		if restingIsBuyer {
+			buyer, seller = resting.Trader, order.Trader
+		}
+		if _, _, err := settlement.Settle(c.store(ctx), buyer, seller, notional, nil); err != nil {
+			restingAccountExists, existsErr := c.accountExists(ctx, resting.Trader)
+			if existsErr != nil {
+				return existsErr
+			}
+			restingAtFault := !restingAccountExists || (restingIsBuyer && errors.Is(err, settlement.ErrInsufficientBalance))
+			if !restingAtFault {
+				// Resting's account is still there and solvent, so
+				// whatever Settle rejected is order's own problem (e.g.
+				// order's trader account went missing, or this is a
+				// self-trade). Evicting resting would wrongly cancel a
+				// solvent counterparty's order for a problem that isn't
+				// its fault; surface the failure instead.
+				return err
+			}
+			// resting's account no longer exists, or resting is the
+			// buyer and is genuinely out of balance (e.g. drained by an
+			// ordinary CreateTransaction transfer since the order was
+			// placed, without the order itself being cancelled): evict
+			// the stale order instead of leaving it stuck in front of
+			// other, solvent orders at the same or worse price — or,
+			// for a never-registered trader, permanently jamming that
+			// price level since CancelOrder would have no real owner to
+			// authorize it — and keep scanning rather than aborting the
+			// whole match.
+			resting.Status = OrderStatusCancelled
+			restingJSON, marshalErr := json.Marshal(resting)
+			if marshalErr != nil {
+				return fmt.Errorf("failed to marshal order: %v", marshalErr)
+			}
+			if err := c.store(ctx).PutOrder(resting.ID, restingJSON); err != nil {
+				return err
+			}
+			if err := c.store(ctx).DeleteBookEntry(entry.Key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		order.RemainingQuantity -= matchQuantity
+		resting.RemainingQuantity -= matchQuantity
+		if resting.RemainingQuantity <= 0 {
+			resting.Status = OrderStatusFilled
+			if err := c.store(ctx).DeleteBookEntry(entry.Key); err != nil {
+				return err
+			}
+		} else {
+			resting.Status = OrderStatusPartiallyFilled
+		}
+
+		restingJSON, err := json.Marshal(resting)
+		if err != nil {
+			return fmt.Errorf("failed to marshal order: %v", err)
+		}
+		if err := c.store(ctx).PutOrder(resting.ID, restingJSON); err != nil {
+			return err
+		}
+
+		tradeTimestamp, err := txTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+
+		bidOrderID, askOrderID := order.ID, resting.ID
+		if order.Side == SideAsk {
+			bidOrderID, askOrderID = resting.ID, order.ID
+		}
+		trade := Trade{
+			ID:         fmt.Sprintf("TRADE_%s_%s", bidOrderID, askOrderID),
+			AssetPair:  order.AssetPair,
+			Price:      resting.Price,
+			Quantity:   matchQuantity,
+			BidOrderID: bidOrderID,
+			AskOrderID: askOrderID,
+			Buyer:      buyer,
+			Seller:     seller,
+			Timestamp:  tradeTimestamp,
+		}
+		tradeJSON, err := json.Marshal(trade)
+		if err != nil {
+			return fmt.Errorf("failed to marshal trade: %v", err)
+		}
+		if err := c.store(ctx).PutTrade(tradeKey(bidOrderID), tradeJSON); err != nil {
+			return err
+		}
+		if err := c.store(ctx).PutTrade(tradeKey(askOrderID), tradeJSON); err != nil {
+			return err
+		}
+		if err := c.store(ctx).EmitEvent("OrderMatched", tradeJSON); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CancelOrder removes orderID's resting quantity from its book, if any, and
+// marks it Cancelled. Orders that are already fully filled or cancelled are
+// left unchanged. Restricted to the order's own Trader.
+func (c *ExchangeContract) CancelOrder(ctx contractapi.TransactionContextInterface, orderID string) error {
+	order, err := c.getOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if callerID != order.Trader {
+		return fmt.Errorf("only the trader of order %s may cancel it", orderID)
+	}
+
+	if order.Status == OrderStatusFilled || order.Status == OrderStatusCancelled {
+		return fmt.Errorf("order %s cannot be cancelled from status %s", orderID, order.Status)
+	}
+
+	if err := c.store(ctx).DeleteBookEntry(bookKey(order.Side, order.AssetPair, order.Price, order.Timestamp, order.ID)); err != nil {
+		return err
+	}
+
+	order.Status = OrderStatusCancelled
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order: %v", err)
+	}
+	if err := c.store(ctx).PutOrder(order.ID, orderJSON); err != nil {
+		return err
+	}
+	return c.store(ctx).EmitEvent("OrderCancelled", orderJSON)
+}
+
+// GetOrderBook returns up to depth resting orders on each side of
+// assetPair, best price first.
+func (c *ExchangeContract) GetOrderBook(ctx contractapi.TransactionContextInterface, assetPair string, depth int32) (*OrderBook, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("depth cannot be negative, got %d", depth)
+	}
+
+	bids, err := c.bookLevels(ctx, SideBid, assetPair, depth)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := c.bookLevels(ctx, SideAsk, assetPair, depth)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderBook{AssetPair: assetPair, Bids: bids, Asks: asks}, nil
+}
+
+func (c *ExchangeContract) bookLevels(ctx contractapi.TransactionContextInterface, side, assetPair string, depth int32) ([]OrderBookLevel, error) {
+	prefix := side + "_" + assetPair + "_"
+	entries, err := c.store(ctx).ListBookEntriesByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, err
+	}
+
+	levels := make([]OrderBookLevel, 0, depth)
+	for _, entry := range entries {
+		if int32(len(levels)) >= depth {
+			break
+		}
+		order, err := c.getOrder(ctx, string(entry.Value))
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, OrderBookLevel{OrderID: order.ID, Price: order.Price, Quantity: order.RemainingQuantity})
+	}
+	return levels, nil
+}
+
+// GetTradeHistory returns every trade orderID has taken part in, most
+// recent first.
+func (c *ExchangeContract) GetTradeHistory(ctx contractapi.TransactionContextInterface, orderID string) ([]*Trade, error) {
+	history, err := c.store(ctx).HistoryOf(tradeKey(orderID))
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []*Trade
+	for _, modification := range history {
+		var trade Trade
+		if err := json.Unmarshal(modification.Value, &trade); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trade: %v", err)
+		}
+		trades = append(trades, &trade)
+	}
+	return trades, nil
+}
+
+// txTimestamp returns the current transaction's timestamp via
+// ctx.GetStub().GetTxTimestamp(), not time.Now(): in a real Fabric
+// deployment this chaincode runs independently on every endorsing peer
+// during simulation, so a time.Now() value would differ peer to peer,
+// and anything derived from it that ends up in a written key or document
+// (order timestamps, book-key timestamps, trade timestamps) would make their
+// write sets diverge and fail endorsement-policy validation.
+// GetTxTimestamp, by contrast, is the timestamp the client stamped into
+// the proposal header, so every endorser sees the same value.
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return ts.AsTime(), nil
+}
+
+func (c *ExchangeContract) getOrder(ctx contractapi.TransactionContextInterface, orderID string) (*Order, error) {
+	orderJSON, err := c.store(ctx).GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if orderJSON == nil {
+		return nil, fmt.Errorf("order with ID %s does not exist", orderID)
+	}
+
+	var order Order
+	if err := json.Unmarshal(orderJSON, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order: %v", err)
+	}
+	return &order, nil
+}
+
+// accountExists reports whether address has a registered Account, so
+// PlaceOrder can reject orders for addresses TransactionContract never
+// registered and match can tell a resting order's account disappearing
+// (never existed, or was never actually an account) apart from it merely
+// running out of balance.
+func (c *ExchangeContract) accountExists(ctx contractapi.TransactionContextInterface, address string) (bool, error) {
+	accountJSON, err := c.store(ctx).GetAccount(address)
+	if err != nil {
+		return false, err
+	}
+	return accountJSON != nil, nil
+}
+
+// crosses reports whether a resting order priced at restingPrice would fill
+// against a newly placed order of side priced at newPrice.
+func crosses(side string, newPrice, restingPrice float64) bool {
+	if side == SideBid {
+		return restingPrice <= newPrice
+	}
+	return restingPrice >= newPrice
+}
+
+// bookKey is the composite key a resting order is indexed under: BIDs sort
+// highest price first, ASKs sort lowest price first, and both break ties
+// oldest-first, giving price-time priority under GetStateByRange.
+func bookKey(side, assetPair string, price float64, ts time.Time, orderID string) string {
+	priceKey := priceKeyAsc(price)
+	if side == SideBid {
+		priceKey = priceKeyDesc(price)
+	}
+	return fmt.Sprintf("%s_%s_%s_%s_%s", side, assetPair, priceKey, tsKeyOf(ts), orderID)
+}
+
+// oppositeBookRange returns the [start, end) range over the book on the
+// other side of side for assetPair, the side PlaceOrder must match against.
+func oppositeBookRange(side, assetPair string) (startKey, endKey string) {
+	oppositeSide := SideAsk
+	if side == SideAsk {
+		oppositeSide = SideBid
+	}
+	prefix := oppositeSide + "_" + assetPair + "_"
+	// "~" sorts after any digit in ASCII, bounding the range the same way
+	// sensorKeyRange does in excursions.go.
+	return prefix, prefix + "~"
+}
+
+func priceKeyAsc(price float64) string {
+	return fmt.Sprintf("%020d", int64(math.Round(price*priceScale)))
+}
+
+func priceKeyDesc(price float64) string {
+	return fmt.Sprintf("%020d", priceInvert-int64(math.Round(price*priceScale)))
+}
+
+func tsKeyOf(ts time.Time) string {
+	return fmt.Sprintf("%020d", ts.UnixNano())
+}
+
+func tradeKey(orderID string) string {
+	return "TRADE_" + orderID
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/crypto"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/dummychain"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// TestPlaceOrderMatchesAtRestingPrice drives PlaceOrder through the
+// dummychain harness: register a buyer and a seller, rest an ask, then
+// cross it with a bid at a higher price. The match must settle at the
+// resting (ask) price, not at raw matched quantity.
+func TestPlaceOrderMatchesAtRestingPrice(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+
+	buyer, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create buyer's client: %v", err)
+	}
+	seller, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create seller's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(accounts, "RegisterAccount", buyer.Address, buyer.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register buyer: %v", err)
+	}
+	if _, err := chain.Invoke(accounts, "RegisterAccount", seller.Address, seller.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register seller: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(seller.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", seller.Address, SideAsk, "XYZ", "100", "10"); err != nil {
+		t.Fatalf("PlaceOrder(ask) failed: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(buyer.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", buyer.Address, SideBid, "XYZ", "110", "10"); err != nil {
+		t.Fatalf("PlaceOrder(bid) failed: %v", err)
+	}
+	chain.NextBlock()
+
+	// A 10-unit trade at price 100 has a notional of 1000: the full
+	// balance each side started with should change hands, not just the
+	// raw 10-unit quantity.
+	buyerJSON, err := chain.Invoke(accounts, "GetAccount", buyer.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(buyer) failed: %v", err)
+	}
+	var buyerAccount Account
+	if err := json.Unmarshal([]byte(buyerJSON), &buyerAccount); err != nil {
+		t.Fatalf("failed to unmarshal buyer's account: %v", err)
+	}
+	if buyerAccount.Balance != 0 {
+		t.Fatalf("expected buyer's balance to be 0 after a 10@100 trade, got %.2f", buyerAccount.Balance)
+	}
+
+	sellerJSON, err := chain.Invoke(accounts, "GetAccount", seller.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(seller) failed: %v", err)
+	}
+	var sellerAccount Account
+	if err := json.Unmarshal([]byte(sellerJSON), &sellerAccount); err != nil {
+		t.Fatalf("failed to unmarshal seller's account: %v", err)
+	}
+	if sellerAccount.Balance != 2000 {
+		t.Fatalf("expected seller's balance to be 2000 after a 10@100 trade, got %.2f", sellerAccount.Balance)
+	}
+
+	bookJSON, err := chain.Invoke(exchange, "GetOrderBook", "XYZ", "10")
+	if err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	var book OrderBook
+	if err := json.Unmarshal([]byte(bookJSON), &book); err != nil {
+		t.Fatalf("failed to unmarshal order book: %v", err)
+	}
+	if len(book.Bids) != 0 || len(book.Asks) != 0 {
+		t.Fatalf("expected a fully matched order book, got %+v", book)
+	}
+}
+
+// TestPlaceOrderRequiresCallerIsTrader drives PlaceOrder through the
+// dummychain harness as an identity other than the trader argument, which
+// must be rejected: otherwise anyone could place (and settle) an order
+// that moves a victim account's balance.
+func TestPlaceOrderRequiresCallerIsTrader(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+
+	victim, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create victim's client: %v", err)
+	}
+	attacker, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create attacker's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(accounts, "RegisterAccount", victim.Address, victim.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register victim: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(attacker.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", victim.Address, SideAsk, "XYZ", "100", "10"); err == nil {
+		t.Fatal("expected PlaceOrder to reject a trader that isn't the caller")
+	}
+
+	bookJSON, err := chain.Invoke(exchange, "GetOrderBook", "XYZ", "10")
+	if err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	var book OrderBook
+	if err := json.Unmarshal([]byte(bookJSON), &book); err != nil {
+		t.Fatalf("failed to unmarshal order book: %v", err)
+	}
+	if len(book.Asks) != 0 {
+		t.Fatalf("expected no order to have been placed for the victim, got %+v", book)
+	}
+}
+
+// TestPlaceOrderRequiresRegisteredTrader drives PlaceOrder through the
+// dummychain harness for a trader address that was never registered with
+// TransactionContract, which must be rejected: an order resting under a
+// made-up address can never settle and, since CancelOrder requires the
+// caller to be that same unreachable address, could never be cancelled
+// either.
+func TestPlaceOrderRequiresRegisteredTrader(t *testing.T) {
+	chain := dummychain.NewChain()
+	exchange := &ExchangeContract{}
+
+	ghost, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create unregistered client: %v", err)
+	}
+
+	if _, err := chain.AsClientIdentity(ghost.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", ghost.Address, SideAsk, "XYZ", "100", "10"); err == nil {
+		t.Fatal("expected PlaceOrder to reject an unregistered trader")
+	}
+}
+
+// TestCancelOrderRequiresTrader drives CancelOrder through the dummychain
+// harness: a trader rests a bid, then an unrelated identity's attempt to
+// cancel it must fail and leave the order resting, while the trader's own
+// cancellation succeeds.
+func TestCancelOrderRequiresTrader(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+
+	trader, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create trader's client: %v", err)
+	}
+	attacker, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create attacker's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(accounts, "RegisterAccount", trader.Address, trader.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register trader: %v", err)
+	}
+	chain.NextBlock()
+
+	orderIDJSON, err := chain.AsClientIdentity(trader.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", trader.Address, SideBid, "XYZ", "100", "10")
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	var orderID string
+	if err := json.Unmarshal([]byte(orderIDJSON), &orderID); err != nil {
+		t.Fatalf("failed to unmarshal order ID: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(attacker.Address, "Org1MSP").Invoke(exchange, "CancelOrder", orderID); err == nil {
+		t.Fatal("expected an unrelated identity's CancelOrder to fail")
+	}
+
+	bookJSON, err := chain.Invoke(exchange, "GetOrderBook", "XYZ", "10")
+	if err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	var book OrderBook
+	if err := json.Unmarshal([]byte(bookJSON), &book); err != nil {
+		t.Fatalf("failed to unmarshal order book: %v", err)
+	}
+	if len(book.Bids) != 1 {
+		t.Fatalf("expected the order to still be resting after a rejected cancel, got %+v", book)
+	}
+
+	if _, err := chain.AsClientIdentity(trader.Address, "Org1MSP").Invoke(exchange, "CancelOrder", orderID); err != nil {
+		t.Fatalf("trader's own CancelOrder failed: %v", err)
+	}
+}
+
+// TestPlaceOrderRejectsSelfTrade drives PlaceOrder through the dummychain
+// harness: a trader rests a bid, then crosses it with their own ask. The
+// match must be rejected rather than silently evicting the trader's own
+// solvent resting order as if it were an insolvency.
+func TestPlaceOrderRejectsSelfTrade(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+
+	trader, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create trader's client: %v", err)
+	}
+	if _, err := chain.Invoke(accounts, "RegisterAccount", trader.Address, trader.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register trader: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(trader.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", trader.Address, SideBid, "XYZ", "100", "10"); err != nil {
+		t.Fatalf("PlaceOrder(bid) failed: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(trader.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", trader.Address, SideAsk, "XYZ", "100", "10"); err == nil {
+		t.Fatal("expected PlaceOrder to reject a self-trade")
+	}
+
+	bookJSON, err := chain.Invoke(exchange, "GetOrderBook", "XYZ", "10")
+	if err != nil {
+		t.Fatalf("GetOrderBook failed: %v", err)
+	}
+	var book OrderBook
+	if err := json.Unmarshal([]byte(bookJSON), &book); err != nil {
+		t.Fatalf("failed to unmarshal order book: %v", err)
+	}
+	if len(book.Bids) != 1 {
+		t.Fatalf("expected the trader's solvent resting bid to survive a rejected self-trade, got %+v", book)
+	}
+}
+
+// TestNewExchangeContractAgainstInMemoryStore drives PlaceOrder through
+// NewExchangeContract(store.NewInMemoryStore(...)) and a matching
+// NewTransactionContract sharing the same store, rather than the
+// FabricStore a bare &ExchangeContract{} falls back to, so the
+// constructor's documented decoupling from ctx.GetStub() actually gets
+// exercised. dummychain.Chain still supplies the transaction context (for
+// GetClientIdentity, GetTxID and GetTxTimestamp); only the world state
+// behind it is swapped out.
+func TestNewExchangeContractAgainstInMemoryStore(t *testing.T) {
+	chain := dummychain.NewChain()
+	memStore := store.NewInMemoryStore(nil)
+	accounts := NewTransactionContract(memStore)
+	exchange := NewExchangeContract(memStore)
+
+	buyer, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create buyer's client: %v", err)
+	}
+	seller, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create seller's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(accounts, "RegisterAccount", buyer.Address, buyer.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register buyer: %v", err)
+	}
+	if _, err := chain.Invoke(accounts, "RegisterAccount", seller.Address, seller.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register seller: %v", err)
+	}
+
+	if _, err := chain.AsClientIdentity(seller.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", seller.Address, SideAsk, "XYZ", "100", "10"); err != nil {
+		t.Fatalf("PlaceOrder(ask) failed: %v", err)
+	}
+	if _, err := chain.AsClientIdentity(buyer.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", buyer.Address, SideBid, "XYZ", "100", "10"); err != nil {
+		t.Fatalf("PlaceOrder(bid) failed: %v", err)
+	}
+
+	sellerJSON, err := chain.Invoke(accounts, "GetAccount", seller.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(seller) failed: %v", err)
+	}
+	var sellerAccount Account
+	if err := json.Unmarshal([]byte(sellerJSON), &sellerAccount); err != nil {
+		t.Fatalf("failed to unmarshal seller's account: %v", err)
+	}
+	if sellerAccount.Balance != 2000 {
+		t.Fatalf("expected seller's balance to be 2000 after a 10@100 trade, got %.2f", sellerAccount.Balance)
+	}
+}
+
+// TestPlaceOrderIDsAreUniqueWithinABlock drives two PlaceOrder calls
+// through the dummychain harness without advancing the block clock
+// between them, so both see the same GetTxTimestamp(). Order IDs must
+// still be distinct (and each order independently retrievable by ID)
+// because they're derived from GetTxID(), not the timestamp: two orders
+// sharing a timestamp must not collide into the same stored document the
+// way they would if ID were still built from a time value alone.
+func TestPlaceOrderIDsAreUniqueWithinABlock(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+
+	buyer, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create buyer's client: %v", err)
+	}
+	seller, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create seller's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(accounts, "RegisterAccount", buyer.Address, buyer.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register buyer: %v", err)
+	}
+	if _, err := chain.Invoke(accounts, "RegisterAccount", seller.Address, seller.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register seller: %v", err)
+	}
+
+	// Deliberately no chain.NextBlock() between these two calls: both see
+	// an identical GetTxTimestamp().
+	askIDJSON, err := chain.AsClientIdentity(seller.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", seller.Address, SideAsk, "XYZ", "100", "10")
+	if err != nil {
+		t.Fatalf("PlaceOrder(ask) failed: %v", err)
+	}
+	bidIDJSON, err := chain.AsClientIdentity(buyer.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", buyer.Address, SideBid, "XYZ", "100", "10")
+	if err != nil {
+		t.Fatalf("PlaceOrder(bid) failed: %v", err)
+	}
+
+	var askID, bidID string
+	if err := json.Unmarshal([]byte(askIDJSON), &askID); err != nil {
+		t.Fatalf("failed to unmarshal ask order ID: %v", err)
+	}
+	if err := json.Unmarshal([]byte(bidIDJSON), &bidID); err != nil {
+		t.Fatalf("failed to unmarshal bid order ID: %v", err)
+	}
+	if askID == bidID {
+		t.Fatalf("expected distinct order IDs, both were %s", askID)
+	}
+
+	tradesJSON, err := chain.Invoke(exchange, "GetTradeHistory", askID)
+	if err != nil {
+		t.Fatalf("GetTradeHistory(ask) failed: %v", err)
+	}
+	var trades []*Trade
+	if err := json.Unmarshal([]byte(tradesJSON), &trades); err != nil {
+		t.Fatalf("failed to unmarshal trade history: %v", err)
+	}
+	if len(trades) != 1 || trades[0].AskOrderID != askID || trades[0].BidOrderID != bidID {
+		t.Fatalf("expected one trade linking the ask and bid order IDs, got %+v", trades)
+	}
+}
+
+// TestGetOrderBookRejectsNegativeDepth drives GetOrderBook through the
+// dummychain harness with a negative depth, which must be rejected rather
+// than panicking on an out-of-range slice capacity.
+func TestGetOrderBookRejectsNegativeDepth(t *testing.T) {
+	chain := dummychain.NewChain()
+	exchange := &ExchangeContract{}
+
+	if _, err := chain.Invoke(exchange, "GetOrderBook", "XYZ", "-1"); err == nil {
+		t.Fatal("expected GetOrderBook to reject a negative depth")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Severity levels for a temperature excursion, ordered least to most
+// serious. Crossing SeverityCritical marks the medicine Compromised.
+const (
+	SeverityMinor    = "Minor"
+	SeverityMajor    = "Major"
+	SeverityCritical = "Critical"
+)
+
+// criticalDeviation is the number of degrees past a bound at which an
+// excursion is considered to have compromised the medicine.
+const criticalDeviation = 5.0
+
+// majorDeviation is the number of degrees past a bound at which an
+// excursion is considered major rather than minor.
+const majorDeviation = 2.0
+
+// Excursion records a single cold-chain bound violation.
+type Excursion struct {
+	Location    string    `json:"location"`
+	Temperature float64   `json:"temperature"`
+	Timestamp   time.Time `json:"timestamp"`
+	Severity    string    `json:"severity"`
+	ReporterID  string    `json:"reporterId"`
+}
+
+// SensorReading is one IoT gateway reading ingested via RecordSensorReading.
+type SensorReading struct {
+	MedicineID  string    `json:"medicineId"`
+	Location    string    `json:"location"`
+	Temperature float64   `json:"temperature"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ExcursionReport is the aggregate computed by GetExcursionReport.
+type ExcursionReport struct {
+	MedicineID             string  `json:"medicineId"`
+	MaxDeviation           float64 `json:"maxDeviation"`
+	TotalMinutesOutOfRange float64 `json:"totalMinutesOutOfRange"`
+	OutOfRangeReadingCount int     `json:"outOfRangeReadingCount"`
+}
+
+// TemperatureExcursionEvent is the payload of a TemperatureExcursion event.
+type TemperatureExcursionEvent struct {
+	MedicineID      string  `json:"medicineId"`
+	Deviation       float64 `json:"deviation"`
+	DurationMinutes float64 `json:"durationMinutes"`
+	Severity        string  `json:"severity"`
+}
+
+// temperatureDeviation returns how far temperature sits outside medicine's
+// bounds and whether it is out of bounds at all. Bounds of (0, 0) mean no
+// bounds have been set, so nothing is ever out of range.
+func temperatureDeviation(medicine *Medicine, temperature float64) (deviation float64, outOfBounds bool) {
+	if medicine.MinTemperature == 0 && medicine.MaxTemperature == 0 {
+		return 0, false
+	}
+	if temperature < medicine.MinTemperature {
+		return medicine.MinTemperature - temperature, true
+	}
+	if temperature > medicine.MaxTemperature {
+		return temperature - medicine.MaxTemperature, true
+	}
+	return 0, false
+}
+
+// classifySeverity buckets a deviation magnitude into a severity level.
+func classifySeverity(deviation float64) string {
+	switch {
+	case deviation >= criticalDeviation:
+		return SeverityCritical
+	case deviation >= majorDeviation:
+		return SeverityMajor
+	default:
+		return SeverityMinor
+	}
+}
+
+// sensorKey is the composite key a sensor reading for medicine id at
+// timestamp ts is stored under. Readings sort chronologically because
+// UnixNano is always the same width for dates in this era.
+func sensorKey(id string, ts time.Time) string {
+	return fmt.Sprintf("SENSOR_%s_%d", id, ts.UnixNano())
+}
+
+// sensorKeyRange returns the [start, end) range covering every reading for
+// medicine id, regardless of timestamp.
+func sensorKeyRange(id string) (startKey, endKey string) {
+	prefix := fmt.Sprintf("SENSOR_%s_", id)
+	// "~" sorts after any digit in ASCII, so this bounds the range without
+	// the off-by-one string(rune(255)) problem the old GetAllTransactions
+	// scan had.
+	return prefix, prefix + "~"
+}
+
+// SetTemperatureBounds sets the cold-chain bounds a medicine must stay
+// within. Restricted to the medicine's current Owner.
+func (c *MedicineTrackingContract) SetTemperatureBounds(ctx contractapi.TransactionContextInterface,
+	id string, minTemperature float64, maxTemperature float64) error {
+
+	medicine, err := c.GetMedicine(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	callerID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity: %v", err)
+	}
+	if callerID != medicine.Owner {
+		return fmt.Errorf("only the owner of medicine %s may set its temperature bounds", id)
+	}
+
+	medicine.MinTemperature = minTemperature
+	medicine.MaxTemperature = maxTemperature
+	medicine.UpdatedAt = time.Now()
+
+	medicineJSON, err := json.Marshal(medicine)
+	if err != nil {
+		return fmt.Errorf("failed to marshal medicine: %v", err)
+	}
+	if err := c.store(ctx).PutMedicine(id, medicineJSON); err != nil {
+		return err
+	}
+	return c.store(ctx).EmitEvent("TemperatureBoundsSet", medicineJSON)
+}
+
+// RecordSensorReading ingests one IoT gateway reading for medicine id.
+// Readings are append-only and do not themselves mutate the medicine
+// record; UpdateMedicineLocation and ReconcileExcursions are what fold them
+// into Medicine.Excursions.
+func (c *MedicineTrackingContract) RecordSensorReading(ctx contractapi.TransactionContextInterface,
+	id string, location string, temperature float64, timestamp string) error {
+
+	exists, err := c.MedicineExists(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check if medicine exists: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("medicine with ID %s does not exist", id)
+	}
+
+	parsedTimestamp, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid reading timestamp: %v", err)
+	}
+
+	return c.putSensorReading(ctx, id, location, temperature, parsedTimestamp)
+}
+
+// putSensorReading appends one reading to medicine id's sensor stream under
+// sensorKey(id, timestamp). It is the shared tail of RecordSensorReading
+// and UpdateMedicineLocation, so a location update always lands in the same
+// stream ReconcileExcursions scans — UpdateMedicineLocation's own
+// timestamp becomes the reading's key, which is what lets
+// ReconcileExcursions' alreadyReconciled dedup recognize an excursion
+// UpdateMedicineLocation already recorded instead of recording it twice.
+func (c *MedicineTrackingContract) putSensorReading(ctx contractapi.TransactionContextInterface,
+	id string, location string, temperature float64, timestamp time.Time) error {
+
+	reading := SensorReading{
+		MedicineID:  id,
+		Location:    location,
+		Temperature: temperature,
+		Timestamp:   timestamp,
+	}
+	readingJSON, err := json.Marshal(reading)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sensor reading: %v", err)
+	}
+
+	if err := c.store(ctx).PutSensorReading(sensorKey(id, timestamp), readingJSON); err != nil {
+		return err
+	}
+	return c.store(ctx).EmitEvent("SensorReadingRecorded", readingJSON)
+}
+
+// GetExcursionReport scans every recorded sensor reading for medicine id
+// and aggregates how far and how long it has run outside its cold-chain
+// bounds. TotalMinutesOutOfRange sums the gap between consecutive
+// out-of-bounds readings plus the gap to the reading that brings each
+// excursion back into bounds, rather than attributing the gap since any
+// prior reading regardless of its own state — a reading that was in
+// bounds just before an excursion started, or the one still out of bounds
+// at the end of the stream with no closing reading yet, contributes
+// nothing to the total.
+func (c *MedicineTrackingContract) GetExcursionReport(ctx contractapi.TransactionContextInterface, id string) (*ExcursionReport, error) {
+	medicine, err := c.GetMedicine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	readings, err := c.listSensorReadings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ExcursionReport{MedicineID: id}
+	var excursionStart time.Time
+	for _, reading := range readings {
+		deviation, outOfBounds := temperatureDeviation(medicine, reading.Temperature)
+		if outOfBounds {
+			if deviation > report.MaxDeviation {
+				report.MaxDeviation = deviation
+			}
+			report.OutOfRangeReadingCount++
+			if !excursionStart.IsZero() {
+				report.TotalMinutesOutOfRange += reading.Timestamp.Sub(excursionStart).Minutes()
+			}
+			excursionStart = reading.Timestamp
+		} else if !excursionStart.IsZero() {
+			report.TotalMinutesOutOfRange += reading.Timestamp.Sub(excursionStart).Minutes()
+			excursionStart = time.Time{}
+		}
+	}
+
+	return report, nil
+}
+
+// ReconcileExcursions folds every sensor reading that violates medicine
+// id's cold-chain bounds into Medicine.Excursions, so on-chain reads of the
+// medicine stay cheap instead of re-scanning the sensor stream each time.
+// Intended to be called periodically by an off-chain scheduled job.
+func (c *MedicineTrackingContract) ReconcileExcursions(ctx contractapi.TransactionContextInterface, id string) error {
+	medicine, err := c.GetMedicine(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	alreadyReconciled := make(map[int64]bool, len(medicine.Excursions))
+	for _, excursion := range medicine.Excursions {
+		alreadyReconciled[excursion.Timestamp.UnixNano()] = true
+	}
+
+	readings, err := c.listSensorReadings(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, reading := range readings {
+		if alreadyReconciled[reading.Timestamp.UnixNano()] {
+			continue
+		}
+		deviation, outOfBounds := temperatureDeviation(medicine, reading.Temperature)
+		if !outOfBounds {
+			continue
+		}
+
+		severity := classifySeverity(deviation)
+		medicine.Excursions = append(medicine.Excursions, Excursion{
+			Location:    reading.Location,
+			Temperature: reading.Temperature,
+			Timestamp:   reading.Timestamp,
+			Severity:    severity,
+			ReporterID:  "reconciliation-job",
+		})
+		if severity == SeverityCritical {
+			medicine.Status = "Compromised"
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	medicine.UpdatedAt = time.Now()
+	medicineJSON, err := json.Marshal(medicine)
+	if err != nil {
+		return fmt.Errorf("failed to marshal medicine: %v", err)
+	}
+	if err := c.store(ctx).PutMedicine(id, medicineJSON); err != nil {
+		return err
+	}
+	return c.store(ctx).EmitEvent("ExcursionsReconciled", medicineJSON)
+}
+
+// listSensorReadings returns every sensor reading recorded for medicine id,
+// oldest first.
+func (c *MedicineTrackingContract) listSensorReadings(ctx contractapi.TransactionContextInterface, id string) ([]SensorReading, error) {
+	startKey, endKey := sensorKeyRange(id)
+	results, err := c.store(ctx).ListSensorReadingsByRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+
+	readings := make([]SensorReading, 0, len(results))
+	for _, result := range results {
+		var reading SensorReading
+		if err := json.Unmarshal(result.Value, &reading); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal sensor reading: %v", err)
+		}
+		readings = append(readings, reading)
+	}
+	return readings, nil
+}
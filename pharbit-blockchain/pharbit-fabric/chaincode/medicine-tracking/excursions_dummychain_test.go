@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/dummychain"
+)
+
+// TestGetExcursionReportCountsOnlyExcursionGaps drives GetExcursionReport
+// through the dummychain harness with an in-bounds reading before an
+// excursion and another closing it, at unevenly spaced timestamps. The
+// report must sum only the gaps between consecutive out-of-bounds readings
+// (and the one that closes the excursion), not the gap since whichever
+// reading happened to come before regardless of its own state.
+func TestGetExcursionReportCountsOnlyExcursionGaps(t *testing.T) {
+	chain := dummychain.NewChain()
+	contract := &MedicineTrackingContract{}
+
+	if _, err := chain.Invoke(contract, "CreateMedicineBatch",
+		"MED1", "Insulin", "BATCH1", "Acme", "2024-01-01", "2025-01-01", "5", "Warehouse"); err != nil {
+		t.Fatalf("CreateMedicineBatch failed: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.Invoke(contract, "SetTemperatureBounds", "MED1", "0", "10"); err != nil {
+		t.Fatalf("SetTemperatureBounds failed: %v", err)
+	}
+	chain.NextBlock()
+
+	readings := []struct {
+		timestamp   string
+		temperature string
+	}{
+		{"2024-06-01T00:00:00Z", "5"},  // in bounds
+		{"2024-06-01T00:05:00Z", "15"}, // excursion starts
+		{"2024-06-01T00:30:00Z", "15"}, // still out of bounds
+		{"2024-06-01T00:32:00Z", "5"},  // back in bounds
+	}
+	for _, reading := range readings {
+		if _, err := chain.Invoke(contract, "RecordSensorReading", "MED1", "Warehouse", reading.temperature, reading.timestamp); err != nil {
+			t.Fatalf("RecordSensorReading(%s) failed: %v", reading.timestamp, err)
+		}
+	}
+	chain.NextBlock()
+
+	reportJSON, err := chain.Invoke(contract, "GetExcursionReport", "MED1")
+	if err != nil {
+		t.Fatalf("GetExcursionReport failed: %v", err)
+	}
+	var report ExcursionReport
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		t.Fatalf("failed to unmarshal excursion report: %v", err)
+	}
+
+	if report.OutOfRangeReadingCount != 2 {
+		t.Fatalf("expected 2 out-of-range readings, got %d", report.OutOfRangeReadingCount)
+	}
+	// 00:05 -> 00:30 (25 min) plus 00:30 -> 00:32 closing the excursion (2
+	// min) = 27 min. The pre-excursion gap from 00:00 (still in bounds) to
+	// 00:05 must not be counted.
+	if report.TotalMinutesOutOfRange != 27 {
+		t.Fatalf("expected 27 minutes out of range, got %.2f", report.TotalMinutesOutOfRange)
+	}
+}
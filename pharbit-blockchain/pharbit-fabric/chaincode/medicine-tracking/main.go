@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// main starts a single chaincode binary serving every contract this
+// package defines: MedicineTrackingContract, TransactionContract and
+// ExchangeContract. contractapi routes each invocation to whichever
+// contract declares the named method, so all three can be installed and
+// instantiated together as one chaincode.
+func main() {
+	chaincode, err := contractapi.NewChaincode(&MedicineTrackingContract{}, &TransactionContract{}, &ExchangeContract{})
+	if err != nil {
+		fmt.Printf("Error creating chaincode: %s", err.Error())
+		return
+	}
+
+	if err := chaincode.Start(); err != nil {
+		fmt.Printf("Error starting chaincode: %s", err.Error())
+	}
+}
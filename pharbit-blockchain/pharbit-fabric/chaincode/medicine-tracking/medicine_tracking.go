@@ -3,31 +3,56 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
 )
 
-// Medicine represents a medicine batch in the supply chain
+// Medicine represents a medicine batch in the supply chain. MinTemperature
+// and MaxTemperature define its cold-chain bounds; Excursions is the
+// reconciled log of out-of-bounds events raised against those bounds.
 type Medicine struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	BatchNumber     string    `json:"batchNumber"`
-	Manufacturer    string    `json:"manufacturer"`
-	ManufactureDate time.Time `json:"manufactureDate"`
-	ExpiryDate      time.Time `json:"expiryDate"`
-	Temperature     float64   `json:"temperature"`
-	Location        string    `json:"location"`
-	Status          string    `json:"status"`
-	Owner           string    `json:"owner"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UpdatedAt       time.Time `json:"updatedAt"`
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	BatchNumber     string      `json:"batchNumber"`
+	Manufacturer    string      `json:"manufacturer"`
+	ManufactureDate time.Time   `json:"manufactureDate"`
+	ExpiryDate      time.Time   `json:"expiryDate"`
+	Temperature     float64     `json:"temperature"`
+	Location        string      `json:"location"`
+	Status          string      `json:"status"`
+	Owner           string      `json:"owner"`
+	MinTemperature  float64     `json:"minTemperature"`
+	MaxTemperature  float64     `json:"maxTemperature"`
+	Excursions      []Excursion `json:"excursions"`
+	CreatedAt       time.Time   `json:"createdAt"`
+	UpdatedAt       time.Time   `json:"updatedAt"`
 }
 
-// MedicineTrackingContract provides functions for managing medicine tracking
+// MedicineTrackingContract provides functions for managing medicine
+// tracking. Store decouples business logic from ctx.GetStub(): when set
+// (typically in tests, via NewMedicineTrackingContract), it is used
+// directly; when left nil (the zero value Fabric constructs the chaincode
+// with), each method wraps that invocation's stub in a FabricStore instead.
 type MedicineTrackingContract struct {
 	contractapi.Contract
+	Store store.Store
+}
+
+// NewMedicineTrackingContract constructs a MedicineTrackingContract against
+// an explicit store, bypassing ctx.GetStub() entirely. Used by tests
+// running against store.NewInMemoryStore and by the dummychain harness.
+func NewMedicineTrackingContract(s store.Store) *MedicineTrackingContract {
+	return &MedicineTrackingContract{Store: s}
+}
+
+func (c *MedicineTrackingContract) store(ctx contractapi.TransactionContextInterface) store.Store {
+	if c.Store != nil {
+		return c.Store
+	}
+	return store.NewFabricStore(ctx.GetStub())
 }
 
 // CreateMedicineBatch creates a new medicine batch (creates a block)
@@ -84,15 +109,15 @@ func (c *MedicineTrackingContract) CreateMedicineBatch(ctx contractapi.Transacti
 	}
 
 	// Store in world state (this creates a block)
-	err = ctx.GetStub().PutState(id, medicineJSON)
+	err = c.store(ctx).PutMedicine(id, medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to put medicine in world state: %v", err)
+		return err
 	}
 
 	// Emit event
-	err = ctx.GetStub().SetEvent("MedicineCreated", medicineJSON)
+	err = c.store(ctx).EmitEvent("MedicineCreated", medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to emit event: %v", err)
+		return err
 	}
 
 	return nil
@@ -100,9 +125,9 @@ func (c *MedicineTrackingContract) CreateMedicineBatch(ctx contractapi.Transacti
 
 // GetMedicine retrieves a medicine by ID
 func (c *MedicineTrackingContract) GetMedicine(ctx contractapi.TransactionContextInterface, id string) (*Medicine, error) {
-	medicineJSON, err := ctx.GetStub().GetState(id)
+	medicineJSON, err := c.store(ctx).GetMedicine(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read medicine from world state: %v", err)
+		return nil, err
 	}
 	if medicineJSON == nil {
 		return nil, fmt.Errorf("medicine with ID %s does not exist", id)
@@ -117,19 +142,65 @@ func (c *MedicineTrackingContract) GetMedicine(ctx contractapi.TransactionContex
 	return &medicine, nil
 }
 
-// UpdateMedicineLocation updates the location and temperature of a medicine (creates a block)
-func (c *MedicineTrackingContract) UpdateMedicineLocation(ctx contractapi.TransactionContextInterface, 
+// UpdateMedicineLocation updates the location and temperature of a medicine
+// (creates a block) and, since a location update is itself a temperature
+// reading, folds it into medicine id's sensor stream the same way
+// RecordSensorReading does (see putSensorReading), so GetExcursionReport
+// and ReconcileExcursions see it too. If newTemperature violates the
+// medicine's cold-chain bounds, it also appends an Excursion, transitions
+// Status to Compromised once severity crosses the critical threshold, and
+// emits a TemperatureExcursion event alongside MedicineUpdated.
+func (c *MedicineTrackingContract) UpdateMedicineLocation(ctx contractapi.TransactionContextInterface,
 	id string, newLocation string, newTemperature float64) error {
-	
+
 	medicine, err := c.GetMedicine(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	previousUpdate := medicine.UpdatedAt
+	now := time.Now()
+
 	// Update medicine details
 	medicine.Location = newLocation
 	medicine.Temperature = newTemperature
-	medicine.UpdatedAt = time.Now()
+	medicine.UpdatedAt = now
+
+	if err := c.putSensorReading(ctx, id, newLocation, newTemperature, now); err != nil {
+		return err
+	}
+
+	if deviation, outOfBounds := temperatureDeviation(medicine, newTemperature); outOfBounds {
+		severity := classifySeverity(deviation)
+		reporterID, err := ctx.GetClientIdentity().GetID()
+		if err != nil {
+			return fmt.Errorf("failed to get client identity: %v", err)
+		}
+
+		medicine.Excursions = append(medicine.Excursions, Excursion{
+			Location:    newLocation,
+			Temperature: newTemperature,
+			Timestamp:   now,
+			Severity:    severity,
+			ReporterID:  reporterID,
+		})
+		if severity == SeverityCritical {
+			medicine.Status = "Compromised"
+		}
+
+		excursionJSON, err := json.Marshal(TemperatureExcursionEvent{
+			MedicineID:      id,
+			Deviation:       deviation,
+			DurationMinutes: now.Sub(previousUpdate).Minutes(),
+			Severity:        severity,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal temperature excursion event: %v", err)
+		}
+		if err := c.store(ctx).EmitEvent("TemperatureExcursion", excursionJSON); err != nil {
+			return err
+		}
+	}
 
 	// Convert to JSON
 	medicineJSON, err := json.Marshal(medicine)
@@ -138,15 +209,15 @@ func (c *MedicineTrackingContract) UpdateMedicineLocation(ctx contractapi.Transa
 	}
 
 	// Store updated medicine (this creates a block)
-	err = ctx.GetStub().PutState(id, medicineJSON)
+	err = c.store(ctx).PutMedicine(id, medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to update medicine in world state: %v", err)
+		return err
 	}
 
 	// Emit event
-	err = ctx.GetStub().SetEvent("MedicineUpdated", medicineJSON)
+	err = c.store(ctx).EmitEvent("MedicineUpdated", medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to emit event: %v", err)
+		return err
 	}
 
 	return nil
@@ -173,15 +244,15 @@ func (c *MedicineTrackingContract) TransferMedicine(ctx contractapi.TransactionC
 	}
 
 	// Store updated medicine (this creates a block)
-	err = ctx.GetStub().PutState(id, medicineJSON)
+	err = c.store(ctx).PutMedicine(id, medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to update medicine in world state: %v", err)
+		return err
 	}
 
 	// Emit event
-	err = ctx.GetStub().SetEvent("MedicineTransferred", medicineJSON)
+	err = c.store(ctx).EmitEvent("MedicineTransferred", medicineJSON)
 	if err != nil {
-		return fmt.Errorf("failed to emit event: %v", err)
+		return err
 	}
 
 	return nil
@@ -189,24 +260,17 @@ func (c *MedicineTrackingContract) TransferMedicine(ctx contractapi.TransactionC
 
 // GetAllMedicines retrieves all medicines from world state
 func (c *MedicineTrackingContract) GetAllMedicines(ctx contractapi.TransactionContextInterface) ([]*Medicine, error) {
-	startKey := ""
-	endKey := ""
+	startKey, endKey := store.MedicineKeyRange()
 
-	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+	results, err := c.store(ctx).ListMedicinesByRange(startKey, endKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
 
 	var medicines []*Medicine
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over results: %v", err)
-		}
-
+	for _, result := range results {
 		var medicine Medicine
-		err = json.Unmarshal(queryResult.Value, &medicine)
+		err = json.Unmarshal(result.Value, &medicine)
 		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal medicine: %v", err)
 		}
@@ -218,30 +282,28 @@ func (c *MedicineTrackingContract) GetAllMedicines(ctx contractapi.TransactionCo
 
 // MedicineExists checks if a medicine exists in world state
 func (c *MedicineTrackingContract) MedicineExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	medicineJSON, err := ctx.GetStub().GetState(id)
+	medicineJSON, err := c.store(ctx).GetMedicine(id)
 	if err != nil {
-		return false, fmt.Errorf("failed to read medicine from world state: %v", err)
+		return false, err
 	}
 	return medicineJSON != nil, nil
 }
 
 // GetMedicineHistory retrieves the history of a medicine
 func (c *MedicineTrackingContract) GetMedicineHistory(ctx contractapi.TransactionContextInterface, id string) ([]*Medicine, error) {
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	key, err := c.store(ctx).MedicineKey(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key: %v", err)
+		return nil, err
+	}
+	history, err := c.store(ctx).HistoryOf(key)
+	if err != nil {
+		return nil, err
 	}
-	defer resultsIterator.Close()
 
 	var medicines []*Medicine
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over history: %v", err)
-		}
-
+	for _, modification := range history {
 		var medicine Medicine
-		err = json.Unmarshal(queryResult.Value, &medicine)
+		err = json.Unmarshal(modification.Value, &medicine)
 		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal medicine: %v", err)
 		}
@@ -249,16 +311,4 @@ func (c *MedicineTrackingContract) GetMedicineHistory(ctx contractapi.Transactio
 	}
 
 	return medicines, nil
-}
-
-func main() {
-	chaincode, err := contractapi.NewChaincode(&MedicineTrackingContract{})
-	if err != nil {
-		fmt.Printf("Error creating medicine tracking chaincode: %s", err.Error())
-		return
-	}
-
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting medicine tracking chaincode: %s", err.Error())
-	}
 }
\ No newline at end of file
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/crypto"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/dummychain"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// TestNewMedicineTrackingContractAgainstInMemoryStore drives
+// CreateMedicineBatch and GetMedicine through
+// NewMedicineTrackingContract(store.NewInMemoryStore(...)) rather than the
+// FabricStore a bare &MedicineTrackingContract{} falls back to, so the
+// constructor's documented decoupling from ctx.GetStub() actually gets
+// exercised. dummychain.Chain still supplies the transaction context (for
+// GetClientIdentity); only the world state behind it is swapped out.
+func TestNewMedicineTrackingContractAgainstInMemoryStore(t *testing.T) {
+	chain := dummychain.NewChain()
+	contract := NewMedicineTrackingContract(store.NewInMemoryStore(nil))
+
+	if _, err := chain.Invoke(contract, "CreateMedicineBatch",
+		"MED1", "Insulin", "BATCH1", "Acme", "2024-01-01", "2025-01-01", "5", "Warehouse"); err != nil {
+		t.Fatalf("CreateMedicineBatch failed: %v", err)
+	}
+
+	medicineJSON, err := chain.Invoke(contract, "GetMedicine", "MED1")
+	if err != nil {
+		t.Fatalf("GetMedicine failed: %v", err)
+	}
+	var medicine Medicine
+	if err := json.Unmarshal([]byte(medicineJSON), &medicine); err != nil {
+		t.Fatalf("failed to unmarshal medicine: %v", err)
+	}
+	if medicine.Status != "Manufactured" {
+		t.Fatalf("expected status Manufactured, got %s", medicine.Status)
+	}
+}
+
+// TestGetAllMedicinesIgnoresOtherContractsKeys drives
+// CreateMedicineBatch, RegisterAccount and PlaceOrder through the same
+// dummychain ledger (as the real deployment does: TransactionContract,
+// ExchangeContract and MedicineTrackingContract are one chaincode sharing
+// one world state, see main.go), then checks that GetAllMedicines returns
+// only the real medicine, not a bogus near-empty Medicine{} unmarshalled
+// from an account or order key.
+func TestGetAllMedicinesIgnoresOtherContractsKeys(t *testing.T) {
+	chain := dummychain.NewChain()
+	accounts := &TransactionContract{}
+	exchange := &ExchangeContract{}
+	medicines := &MedicineTrackingContract{}
+
+	trader, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create trader's client: %v", err)
+	}
+	if _, err := chain.Invoke(accounts, "RegisterAccount", trader.Address, trader.PublicKey, "1000"); err != nil {
+		t.Fatalf("failed to register trader: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.AsClientIdentity(trader.Address, "Org1MSP").Invoke(exchange, "PlaceOrder", trader.Address, SideBid, "XYZ", "100", "10"); err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+	chain.NextBlock()
+
+	if _, err := chain.Invoke(medicines, "CreateMedicineBatch",
+		"MED1", "Insulin", "BATCH1", "Acme", "2024-01-01", "2025-01-01", "5", "Warehouse"); err != nil {
+		t.Fatalf("CreateMedicineBatch failed: %v", err)
+	}
+	chain.NextBlock()
+
+	allJSON, err := chain.Invoke(medicines, "GetAllMedicines")
+	if err != nil {
+		t.Fatalf("GetAllMedicines failed: %v", err)
+	}
+	var all []*Medicine
+	if err := json.Unmarshal([]byte(allJSON), &all); err != nil {
+		t.Fatalf("failed to unmarshal medicines: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != "MED1" {
+		t.Fatalf("expected only MED1, got %+v", all)
+	}
+}
@@ -3,41 +3,88 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/crypto"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/settlement"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// Transaction docTypes, stamped on the JSON document so QueryTransactions'
+// and QueryAccounts' CouchDB selectors can pick out documents of each kind
+// alongside whatever field they're actually filtering on (see
+// META-INF/statedb/couchdb/indexes).
+const (
+	transactionDocType = "transaction"
+	accountDocType     = "account"
 )
 
 // Transaction represents a blockchain transaction
 type Transaction struct {
+	DocType   string    `json:"docType"`
 	ID        string    `json:"id"`
 	Sender    string    `json:"sender"`
 	Receiver  string    `json:"receiver"`
 	Quantity  float64   `json:"quantity"`
+	Nonce     uint64    `json:"nonce"`
 	Timestamp time.Time `json:"timestamp"`
 	Status    string    `json:"status"`
 	BlockHash string    `json:"blockHash"`
 	TxHash    string    `json:"txHash"`
 }
 
-// Account represents a user account with balance
-type Account struct {
-	Address string  `json:"address"`
-	Balance float64 `json:"balance"`
-	Created time.Time `json:"created"`
-	Updated time.Time `json:"updated"`
-}
-
-// TransactionContract provides functions for managing transactions
+// Account represents a user account with balance. It is the canonical
+// settlement.Account: CreateTransaction settles transfers through the same
+// debit/credit logic ExchangeContract uses to settle matched trades, so
+// both need the identical on-disk shape. PublicKey is the hex-encoded
+// ed25519 public key registered for Address; Nonce is the sequence number
+// of the last transaction accepted from this account, used to reject
+// replays and out-of-order resubmission.
+type Account = settlement.Account
+
+// TransactionContract provides functions for managing transactions. Store
+// decouples business logic from ctx.GetStub(): when set (typically in
+// tests, via NewTransactionContract), it is used directly; when left nil
+// (the zero value Fabric constructs the chaincode with), each method wraps
+// that invocation's stub in a FabricStore instead.
 type TransactionContract struct {
 	contractapi.Contract
+	Store store.Store
 }
 
-// CreateTransaction creates a new transaction (creates a block)
-func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionContextInterface, 
-	sender string, receiver string, quantity float64) error {
-	
+// NewTransactionContract constructs a TransactionContract against an
+// explicit store, bypassing ctx.GetStub() entirely. Used by tests running
+// against store.NewInMemoryStore and by the dummychain harness.
+func NewTransactionContract(s store.Store) *TransactionContract {
+	return &TransactionContract{Store: s}
+}
+
+func (c *TransactionContract) store(ctx contractapi.TransactionContextInterface) store.Store {
+	if c.Store != nil {
+		return c.Store
+	}
+	return store.NewFabricStore(ctx.GetStub())
+}
+
+// CreateTransaction verifies and applies an off-chain signed transfer
+// (creates a block). signedTransactionJSON is the marshalled JSON of a
+// crypto.SignedTransaction: {sender, receiver, quantity, nonce, signature}.
+// The sender is never trusted as a bare parameter; the signature must
+// verify against the public key already registered for that sender.
+func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionContextInterface,
+	signedTransactionJSON string) error {
+
+	var signedTx crypto.SignedTransaction
+	if err := json.Unmarshal([]byte(signedTransactionJSON), &signedTx); err != nil {
+		return fmt.Errorf("failed to unmarshal signed transaction: %v", err)
+	}
+
+	sender := signedTx.Sender
+	receiver := signedTx.Receiver
+	quantity := signedTx.Quantity
+
 	// Validate inputs
 	if sender == "" || receiver == "" {
 		return fmt.Errorf("sender and receiver addresses cannot be empty")
@@ -49,35 +96,49 @@ func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionConte
 		return fmt.Errorf("sender and receiver cannot be the same")
 	}
 
-	// Check if sender has sufficient balance
 	senderAccount, err := c.GetAccount(ctx, sender)
 	if err != nil {
-		// Create account if it doesn't exist
-		senderAccount = &Account{
-			Address: sender,
-			Balance: 0,
-			Created: time.Now(),
-			Updated: time.Now(),
-		}
+		return fmt.Errorf("sender account does not exist: %v", err)
+	}
+
+	// Verify the signature against the sender's registered public key. This
+	// is the actual authentication: it proves whoever submitted
+	// signedTransactionJSON holds the private key for senderAccount.PublicKey.
+	// (RegisterAccount already pins sender's address to that key's hash at
+	// registration time, so re-deriving and comparing the address here
+	// would be tautological, not an extra check.)
+	if err := crypto.Verify(senderAccount.PublicKey, signedTx.Signature, signedTx); err != nil {
+		return fmt.Errorf("signature verification failed: %v", err)
 	}
 
+	// Require strictly sequential nonces to reject replays and reorderings.
+	if signedTx.Nonce != senderAccount.Nonce+1 {
+		return fmt.Errorf("invalid nonce: expected %d, got %d", senderAccount.Nonce+1, signedTx.Nonce)
+	}
 	if senderAccount.Balance < quantity {
 		return fmt.Errorf("insufficient balance. Sender has %.2f, trying to send %.2f", senderAccount.Balance, quantity)
 	}
 
 	// Generate transaction ID
 	txID := fmt.Sprintf("TX_%d", time.Now().UnixNano())
-	
+
+	txHash, err := crypto.Hash(signedTx)
+	if err != nil {
+		return fmt.Errorf("failed to hash transaction: %v", err)
+	}
+
 	// Create transaction
 	transaction := Transaction{
+		DocType:   transactionDocType,
 		ID:        txID,
 		Sender:    sender,
 		Receiver:  receiver,
 		Quantity:  quantity,
+		Nonce:     signedTx.Nonce,
 		Timestamp: time.Now(),
 		Status:    "Pending",
 		BlockHash: "",
-		TxHash:    fmt.Sprintf("0x%x", time.Now().UnixNano()),
+		TxHash:    txHash,
 	}
 
 	// Convert to JSON
@@ -87,42 +148,20 @@ func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionConte
 	}
 
 	// Store transaction in world state (this creates a block)
-	err = ctx.GetStub().PutState(txID, transactionJSON)
-	if err != nil {
-		return fmt.Errorf("failed to put transaction in world state: %v", err)
-	}
-
-	// Update sender balance
-	senderAccount.Balance -= quantity
-	senderAccount.Updated = time.Now()
-	senderAccountJSON, err := json.Marshal(senderAccount)
+	err = c.store(ctx).PutTransaction(txID, transactionJSON)
 	if err != nil {
-		return fmt.Errorf("failed to marshal sender account: %v", err)
-	}
-	err = ctx.GetStub().PutState(sender, senderAccountJSON)
-	if err != nil {
-		return fmt.Errorf("failed to update sender account: %v", err)
+		return err
 	}
 
-	// Update or create receiver account
-	receiverAccount, err := c.GetAccount(ctx, receiver)
-	if err != nil {
-		receiverAccount = &Account{
-			Address: receiver,
-			Balance: 0,
-			Created: time.Now(),
-			Updated: time.Now(),
-		}
-	}
-	receiverAccount.Balance += quantity
-	receiverAccount.Updated = time.Now()
-	receiverAccountJSON, err := json.Marshal(receiverAccount)
-	if err != nil {
-		return fmt.Errorf("failed to marshal receiver account: %v", err)
-	}
-	err = ctx.GetStub().PutState(receiver, receiverAccountJSON)
+	// Debit the sender and credit the receiver, advancing the sender's
+	// nonce so this transaction cannot be replayed. Receiver must already
+	// be registered; this is an account-based ledger, not a bearer system
+	// that mints accounts on first transfer.
+	_, _, err = settlement.Settle(c.store(ctx), sender, receiver, quantity, func(account *Account) {
+		account.Nonce = signedTx.Nonce
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update receiver account: %v", err)
+		return err
 	}
 
 	// Update transaction status to completed
@@ -131,15 +170,15 @@ func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionConte
 	if err != nil {
 		return fmt.Errorf("failed to marshal updated transaction: %v", err)
 	}
-	err = ctx.GetStub().PutState(txID, transactionJSON)
+	err = c.store(ctx).PutTransaction(txID, transactionJSON)
 	if err != nil {
-		return fmt.Errorf("failed to update transaction status: %v", err)
+		return err
 	}
 
 	// Emit event
-	err = ctx.GetStub().SetEvent("TransactionCreated", transactionJSON)
+	err = c.store(ctx).EmitEvent("TransactionCreated", transactionJSON)
 	if err != nil {
-		return fmt.Errorf("failed to emit event: %v", err)
+		return err
 	}
 
 	return nil
@@ -147,9 +186,9 @@ func (c *TransactionContract) CreateTransaction(ctx contractapi.TransactionConte
 
 // GetTransaction retrieves a transaction by ID
 func (c *TransactionContract) GetTransaction(ctx contractapi.TransactionContextInterface, txID string) (*Transaction, error) {
-	transactionJSON, err := ctx.GetStub().GetState(txID)
+	transactionJSON, err := c.store(ctx).GetTransaction(txID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read transaction from world state: %v", err)
+		return nil, err
 	}
 	if transactionJSON == nil {
 		return nil, fmt.Errorf("transaction with ID %s does not exist", txID)
@@ -166,9 +205,9 @@ func (c *TransactionContract) GetTransaction(ctx contractapi.TransactionContextI
 
 // GetAccount retrieves an account by address
 func (c *TransactionContract) GetAccount(ctx contractapi.TransactionContextInterface, address string) (*Account, error) {
-	accountJSON, err := ctx.GetStub().GetState(address)
+	accountJSON, err := c.store(ctx).GetAccount(address)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read account from world state: %v", err)
+		return nil, err
 	}
 	if accountJSON == nil {
 		return nil, fmt.Errorf("account with address %s does not exist", address)
@@ -183,10 +222,20 @@ func (c *TransactionContract) GetAccount(ctx contractapi.TransactionContextInter
 	return &account, nil
 }
 
-// CreateAccount creates a new account with initial balance
-func (c *TransactionContract) CreateAccount(ctx contractapi.TransactionContextInterface, 
-	address string, initialBalance float64) error {
-	
+// CreateAccount creates a new account with initial balance. pubKeyHex must
+// be the hex-encoded ed25519 public key the account will sign transactions
+// with; address must match the address derived from it.
+func (c *TransactionContract) CreateAccount(ctx contractapi.TransactionContextInterface,
+	address string, pubKeyHex string, initialBalance float64) error {
+	return c.RegisterAccount(ctx, address, pubKeyHex, initialBalance)
+}
+
+// RegisterAccount creates a new account bound to pubKeyHex. address must
+// equal crypto.AddressFromPublicKey(pubKeyHex) so the stored address always
+// reflects the key that controls it.
+func (c *TransactionContract) RegisterAccount(ctx contractapi.TransactionContextInterface,
+	address string, pubKeyHex string, initialBalance float64) error {
+
 	// Check if account already exists
 	exists, err := c.AccountExists(ctx, address)
 	if err != nil {
@@ -196,12 +245,23 @@ func (c *TransactionContract) CreateAccount(ctx contractapi.TransactionContextIn
 		return fmt.Errorf("account with address %s already exists", address)
 	}
 
+	expectedAddress, err := crypto.AddressFromPublicKey(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+	if expectedAddress != address {
+		return fmt.Errorf("address %s does not match the address derived from the public key", address)
+	}
+
 	// Create new account
 	account := Account{
-		Address: address,
-		Balance: initialBalance,
-		Created: time.Now(),
-		Updated: time.Now(),
+		DocType:   accountDocType,
+		Address:   address,
+		Balance:   initialBalance,
+		PublicKey: pubKeyHex,
+		Nonce:     0,
+		Created:   time.Now(),
+		Updated:   time.Now(),
 	}
 
 	// Convert to JSON
@@ -211,109 +271,120 @@ func (c *TransactionContract) CreateAccount(ctx contractapi.TransactionContextIn
 	}
 
 	// Store in world state (this creates a block)
-	err = ctx.GetStub().PutState(address, accountJSON)
+	err = c.store(ctx).PutAccount(address, accountJSON)
 	if err != nil {
-		return fmt.Errorf("failed to put account in world state: %v", err)
+		return err
 	}
 
 	// Emit event
-	err = ctx.GetStub().SetEvent("AccountCreated", accountJSON)
+	err = c.store(ctx).EmitEvent("AccountCreated", accountJSON)
 	if err != nil {
-		return fmt.Errorf("failed to emit event: %v", err)
+		return err
 	}
 
 	return nil
 }
 
-// GetAllTransactions retrieves all transactions from world state
-func (c *TransactionContract) GetAllTransactions(ctx contractapi.TransactionContextInterface) ([]*Transaction, error) {
-	startKey := "TX_"
-	endKey := "TX_" + string(rune(255))
+// TransactionQueryResult is one page of a QueryTransactions rich query.
+type TransactionQueryResult struct {
+	Transactions   []*Transaction `json:"transactions"`
+	Bookmark       string         `json:"bookmark"`
+	FetchedRecords int32          `json:"fetchedRecords"`
+}
+
+// AccountQueryResult is one page of a QueryAccounts rich query.
+type AccountQueryResult struct {
+	Accounts       []*Account `json:"accounts"`
+	Bookmark       string     `json:"bookmark"`
+	FetchedRecords int32      `json:"fetchedRecords"`
+}
 
-	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+// QueryTransactions runs a CouchDB Mango selector against transaction
+// documents and returns one page of matches, replacing the old
+// GetAllTransactions full scan (whose "TX_" + string(rune(255)) upper
+// bound didn't even produce the intended range in UTF-8). selector is a
+// full query document, e.g.
+// `{"selector":{"docType":"transaction","sender":"addr1"}}`; pageSize
+// bounds how many documents come back; bookmark resumes after a previous
+// page ("" for the first page). See META-INF/statedb/couchdb/indexes for
+// the indexes that keep selectors on sender, receiver, status and
+// timestamp fast.
+func (c *TransactionContract) QueryTransactions(ctx contractapi.TransactionContextInterface,
+	selector string, pageSize int32, bookmark string) (*TransactionQueryResult, error) {
+
+	page, err := c.store(ctx).QueryTransactions(selector, pageSize, bookmark)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var transactions []*Transaction
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over results: %v", err)
-		}
 
+	transactions := make([]*Transaction, 0, len(page.Results))
+	for _, result := range page.Results {
 		var transaction Transaction
-		err = json.Unmarshal(queryResult.Value, &transaction)
-		if err != nil {
+		if err := json.Unmarshal(result.Value, &transaction); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal transaction: %v", err)
 		}
 		transactions = append(transactions, &transaction)
 	}
 
-	return transactions, nil
+	return &TransactionQueryResult{
+		Transactions:   transactions,
+		Bookmark:       page.Bookmark,
+		FetchedRecords: page.FetchedRecords,
+	}, nil
 }
 
-// GetAllAccounts retrieves all accounts from world state
-func (c *TransactionContract) GetAllAccounts(ctx contractapi.TransactionContextInterface) ([]*Account, error) {
-	startKey := ""
-	endKey := ""
+// QueryAccounts is QueryTransactions' counterpart over account documents,
+// replacing the old GetAllAccounts full scan that filtered out transaction
+// records by checking key prefixes in Go. Indexed for balance-range
+// selectors (see META-INF/statedb/couchdb/indexes).
+func (c *TransactionContract) QueryAccounts(ctx contractapi.TransactionContextInterface,
+	selector string, pageSize int32, bookmark string) (*AccountQueryResult, error) {
 
-	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+	page, err := c.store(ctx).QueryAccounts(selector, pageSize, bookmark)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get state by range: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var accounts []*Account
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over results: %v", err)
-		}
-
-		// Skip transaction records
-		if len(queryResult.Key) >= 3 && queryResult.Key[:3] == "TX_" {
-			continue
-		}
 
+	accounts := make([]*Account, 0, len(page.Results))
+	for _, result := range page.Results {
 		var account Account
-		err = json.Unmarshal(queryResult.Value, &account)
-		if err != nil {
+		if err := json.Unmarshal(result.Value, &account); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal account: %v", err)
 		}
 		accounts = append(accounts, &account)
 	}
 
-	return accounts, nil
+	return &AccountQueryResult{
+		Accounts:       accounts,
+		Bookmark:       page.Bookmark,
+		FetchedRecords: page.FetchedRecords,
+	}, nil
 }
 
 // AccountExists checks if an account exists in world state
 func (c *TransactionContract) AccountExists(ctx contractapi.TransactionContextInterface, address string) (bool, error) {
-	accountJSON, err := ctx.GetStub().GetState(address)
+	accountJSON, err := c.store(ctx).GetAccount(address)
 	if err != nil {
-		return false, fmt.Errorf("failed to read account from world state: %v", err)
+		return false, err
 	}
 	return accountJSON != nil, nil
 }
 
 // GetTransactionHistory retrieves the history of a transaction
 func (c *TransactionContract) GetTransactionHistory(ctx contractapi.TransactionContextInterface, txID string) ([]*Transaction, error) {
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(txID)
+	key, err := c.store(ctx).TransactionKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := c.store(ctx).HistoryOf(key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
 
 	var transactions []*Transaction
-	for resultsIterator.HasNext() {
-		queryResult, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate over history: %v", err)
-		}
-
+	for _, modification := range history {
 		var transaction Transaction
-		err = json.Unmarshal(queryResult.Value, &transaction)
+		err = json.Unmarshal(modification.Value, &transaction)
 		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal transaction: %v", err)
 		}
@@ -321,16 +392,4 @@ func (c *TransactionContract) GetTransactionHistory(ctx contractapi.TransactionC
 	}
 
 	return transactions, nil
-}
-
-func main() {
-	chaincode, err := contractapi.NewChaincode(&TransactionContract{})
-	if err != nil {
-		fmt.Printf("Error creating transaction tracking chaincode: %s", err.Error())
-		return
-	}
-
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting transaction tracking chaincode: %s", err.Error())
-	}
 }
\ No newline at end of file
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/crypto"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/dummychain"
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// TestCreateTransactionEndToEnd drives CreateTransaction through the
+// dummychain harness: register two accounts, sign and submit a transfer,
+// then check the resulting balances and transaction history. No Docker or
+// Fabric network is involved.
+func TestCreateTransactionEndToEnd(t *testing.T) {
+	chain := dummychain.NewChain()
+	contract := &TransactionContract{}
+
+	alice, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create alice's client: %v", err)
+	}
+	bob, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create bob's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(contract, "RegisterAccount", alice.Address, alice.PublicKey, "100"); err != nil {
+		t.Fatalf("failed to register alice: %v", err)
+	}
+	if _, err := chain.Invoke(contract, "RegisterAccount", bob.Address, bob.PublicKey, "0"); err != nil {
+		t.Fatalf("failed to register bob: %v", err)
+	}
+	chain.NextBlock()
+
+	signedTx, err := alice.BuildSignedTransaction(bob.Address, 40)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if _, err := chain.Invoke(contract, "CreateTransaction", string(signedTx)); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+	chain.NextBlock()
+
+	aliceJSON, err := chain.Invoke(contract, "GetAccount", alice.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(alice) failed: %v", err)
+	}
+	var aliceAccount Account
+	if err := json.Unmarshal([]byte(aliceJSON), &aliceAccount); err != nil {
+		t.Fatalf("failed to unmarshal alice's account: %v", err)
+	}
+	if aliceAccount.Balance != 60 {
+		t.Fatalf("expected alice's balance to be 60, got %.2f", aliceAccount.Balance)
+	}
+	if aliceAccount.Nonce != 1 {
+		t.Fatalf("expected alice's nonce to be 1, got %d", aliceAccount.Nonce)
+	}
+
+	bobJSON, err := chain.Invoke(contract, "GetAccount", bob.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(bob) failed: %v", err)
+	}
+	var bobAccount Account
+	if err := json.Unmarshal([]byte(bobJSON), &bobAccount); err != nil {
+		t.Fatalf("failed to unmarshal bob's account: %v", err)
+	}
+	if bobAccount.Balance != 40 {
+		t.Fatalf("expected bob's balance to be 40, got %.2f", bobAccount.Balance)
+	}
+
+	queryJSON, err := chain.Invoke(contract, "QueryTransactions", `{"selector":{"docType":"transaction","sender":"`+alice.Address+`"}}`, "10", "")
+	if err != nil {
+		t.Fatalf("QueryTransactions failed: %v", err)
+	}
+	var queryResult TransactionQueryResult
+	if err := json.Unmarshal([]byte(queryJSON), &queryResult); err != nil {
+		t.Fatalf("failed to unmarshal query result: %v", err)
+	}
+	if len(queryResult.Transactions) != 1 || queryResult.FetchedRecords != 1 {
+		t.Fatalf("expected 1 transaction, got %+v", queryResult)
+	}
+	transactions := queryResult.Transactions
+
+	historyJSON, err := chain.Invoke(contract, "GetTransactionHistory", transactions[0].ID)
+	if err != nil {
+		t.Fatalf("GetTransactionHistory failed: %v", err)
+	}
+	var history []*Transaction
+	if err := json.Unmarshal([]byte(historyJSON), &history); err != nil {
+		t.Fatalf("failed to unmarshal transaction history: %v", err)
+	}
+	// Pending then Completed, most recent first.
+	if len(history) != 2 || history[0].Status != "Completed" || history[1].Status != "Pending" {
+		t.Fatalf("unexpected transaction history: %+v", history)
+	}
+
+	// Replaying the same signed transaction must fail: the nonce has
+	// already been consumed.
+	if _, err := chain.Invoke(contract, "CreateTransaction", string(signedTx)); err == nil {
+		t.Fatal("expected replay of a spent nonce to fail")
+	}
+}
+
+// TestNewTransactionContractAgainstInMemoryStore drives TransactionContract
+// through NewTransactionContract(store.NewInMemoryStore(...)) rather than
+// the FabricStore a bare &TransactionContract{} falls back to, so the
+// constructor's documented decoupling from ctx.GetStub() actually gets
+// exercised. dummychain.Chain still supplies the transaction context each
+// method takes; only the world state behind it is swapped out.
+func TestNewTransactionContractAgainstInMemoryStore(t *testing.T) {
+	chain := dummychain.NewChain()
+	contract := NewTransactionContract(store.NewInMemoryStore(nil))
+
+	alice, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create alice's client: %v", err)
+	}
+	bob, err := crypto.NewClient()
+	if err != nil {
+		t.Fatalf("failed to create bob's client: %v", err)
+	}
+
+	if _, err := chain.Invoke(contract, "RegisterAccount", alice.Address, alice.PublicKey, "100"); err != nil {
+		t.Fatalf("failed to register alice: %v", err)
+	}
+	if _, err := chain.Invoke(contract, "RegisterAccount", bob.Address, bob.PublicKey, "0"); err != nil {
+		t.Fatalf("failed to register bob: %v", err)
+	}
+
+	signedTx, err := alice.BuildSignedTransaction(bob.Address, 40)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if _, err := chain.Invoke(contract, "CreateTransaction", string(signedTx)); err != nil {
+		t.Fatalf("CreateTransaction failed: %v", err)
+	}
+
+	bobJSON, err := chain.Invoke(contract, "GetAccount", bob.Address)
+	if err != nil {
+		t.Fatalf("GetAccount(bob) failed: %v", err)
+	}
+	var bobAccount Account
+	if err := json.Unmarshal([]byte(bobJSON), &bobAccount); err != nil {
+		t.Fatalf("failed to unmarshal bob's account: %v", err)
+	}
+	if bobAccount.Balance != 40 {
+		t.Fatalf("expected bob's balance to be 40, got %.2f", bobAccount.Balance)
+	}
+}
@@ -0,0 +1,107 @@
+// Package settlement holds the account debit/credit logic shared by
+// TransactionContract.CreateTransaction and ExchangeContract's order
+// matching, so both move balances between accounts the same way.
+package settlement
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codeemail003/Blockchain/pharbit-blockchain/pharbit-fabric/chaincode/store"
+)
+
+// ErrInsufficientBalance is wrapped into the error Settle returns when
+// senderAddress cannot cover quantity, so callers that need to distinguish
+// an insolvent sender from Settle's other failure modes (self-transfer, a
+// missing account) can check for it with errors.Is instead of inferring the
+// cause from which side they expected to fail.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Account represents a user account with balance. PublicKey is the
+// hex-encoded ed25519 public key registered for Address; Nonce is the
+// sequence number of the last off-chain signed transaction accepted from
+// this account. DocType identifies the document as an account to the
+// CouchDB selectors TransactionContract.QueryAccounts runs; it is stamped
+// once at RegisterAccount and simply carried through every rewrite Settle
+// does.
+type Account struct {
+	DocType   string    `json:"docType"`
+	Address   string    `json:"address"`
+	Balance   float64   `json:"balance"`
+	PublicKey string    `json:"publicKey"`
+	Nonce     uint64    `json:"nonce"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Settle debits quantity from senderAddress and credits it to
+// receiverAddress atomically, returning both accounts as they were left.
+// mutateSender, if non-nil, runs after the debit but before the account is
+// persisted, so callers can stamp fields specific to their settlement (for
+// example TransactionContract bumping the sender's nonce).
+func Settle(s store.Store, senderAddress, receiverAddress string, quantity float64, mutateSender func(*Account)) (sender *Account, receiver *Account, err error) {
+	if quantity <= 0 {
+		return nil, nil, fmt.Errorf("quantity must be greater than 0")
+	}
+	if senderAddress == receiverAddress {
+		return nil, nil, fmt.Errorf("sender and receiver cannot be the same")
+	}
+
+	sender, err = getAccount(s, senderAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sender account does not exist: %v", err)
+	}
+	receiver, err = getAccount(s, receiverAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("receiver account does not exist: %v", err)
+	}
+
+	if sender.Balance < quantity {
+		return nil, nil, fmt.Errorf("%w: sender has %.2f, trying to send %.2f", ErrInsufficientBalance, sender.Balance, quantity)
+	}
+
+	now := time.Now()
+	sender.Balance -= quantity
+	sender.Updated = now
+	if mutateSender != nil {
+		mutateSender(sender)
+	}
+
+	receiver.Balance += quantity
+	receiver.Updated = now
+
+	if err := putAccount(s, senderAddress, sender); err != nil {
+		return nil, nil, err
+	}
+	if err := putAccount(s, receiverAddress, receiver); err != nil {
+		return nil, nil, err
+	}
+
+	return sender, receiver, nil
+}
+
+func getAccount(s store.Store, address string) (*Account, error) {
+	accountJSON, err := s.GetAccount(address)
+	if err != nil {
+		return nil, err
+	}
+	if accountJSON == nil {
+		return nil, fmt.Errorf("account with address %s does not exist", address)
+	}
+
+	var account Account
+	if err := json.Unmarshal(accountJSON, &account); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal account: %v", err)
+	}
+	return &account, nil
+}
+
+func putAccount(s store.Store, address string, account *Account) error {
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account: %v", err)
+	}
+	return s.PutAccount(address, accountJSON)
+}
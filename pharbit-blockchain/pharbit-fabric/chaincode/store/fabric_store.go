@@ -0,0 +1,240 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// FabricStore implements Store by delegating to a Fabric chaincode stub.
+// This is the production backend: every call goes to the stub, as the
+// contracts' direct ctx.GetStub() calls did, but transactions, accounts and
+// medicines are stored under composite keys built by the stub's
+// CreateCompositeKey rather than under their raw IDs.
+type FabricStore struct {
+	stub shim.ChaincodeStubInterface
+}
+
+// NewFabricStore wraps stub, normally obtained from ctx.GetStub() inside a
+// contract method, in a Store.
+func NewFabricStore(stub shim.ChaincodeStubInterface) *FabricStore {
+	return &FabricStore{stub: stub}
+}
+
+func (s *FabricStore) GetTransaction(txID string) ([]byte, error) {
+	key, err := s.TransactionKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(key)
+}
+
+func (s *FabricStore) PutTransaction(txID string, value []byte) error {
+	key, err := s.TransactionKey(txID)
+	if err != nil {
+		return err
+	}
+	return s.put(key, value)
+}
+
+// TransactionKey builds the composite key a transaction is stored under
+// via ctx.GetStub().CreateCompositeKey, so it goes through Fabric's own
+// composite-key encoding and attribute validation rather than a hand-rolled
+// copy of the format.
+func (s *FabricStore) TransactionKey(txID string) (string, error) {
+	key, err := s.stub.CreateCompositeKey(transactionObjectType, []string{txID})
+	if err != nil {
+		return "", fmt.Errorf("failed to build transaction key: %v", err)
+	}
+	return key, nil
+}
+
+func (s *FabricStore) QueryTransactions(selector string, pageSize int32, bookmark string) (QueryPage, error) {
+	return s.richQuery(selector, pageSize, bookmark)
+}
+
+func (s *FabricStore) GetAccount(address string) ([]byte, error) {
+	key, err := s.AccountKey(address)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(key)
+}
+
+func (s *FabricStore) PutAccount(address string, value []byte) error {
+	key, err := s.AccountKey(address)
+	if err != nil {
+		return err
+	}
+	return s.put(key, value)
+}
+
+// AccountKey is TransactionKey's counterpart for an account address.
+func (s *FabricStore) AccountKey(address string) (string, error) {
+	key, err := s.stub.CreateCompositeKey(accountObjectType, []string{address})
+	if err != nil {
+		return "", fmt.Errorf("failed to build account key: %v", err)
+	}
+	return key, nil
+}
+
+func (s *FabricStore) QueryAccounts(selector string, pageSize int32, bookmark string) (QueryPage, error) {
+	return s.richQuery(selector, pageSize, bookmark)
+}
+
+func (s *FabricStore) GetMedicine(id string) ([]byte, error) {
+	key, err := s.MedicineKey(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(key)
+}
+
+func (s *FabricStore) PutMedicine(id string, value []byte) error {
+	key, err := s.MedicineKey(id)
+	if err != nil {
+		return err
+	}
+	return s.put(key, value)
+}
+
+// MedicineKey is TransactionKey/AccountKey's counterpart for a medicine ID.
+func (s *FabricStore) MedicineKey(id string) (string, error) {
+	key, err := s.stub.CreateCompositeKey(medicineObjectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("failed to build medicine key: %v", err)
+	}
+	return key, nil
+}
+
+func (s *FabricStore) ListMedicinesByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *FabricStore) PutSensorReading(key string, value []byte) error {
+	return s.put(key, value)
+}
+
+func (s *FabricStore) ListSensorReadingsByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *FabricStore) GetOrder(orderID string) ([]byte, error) {
+	return s.get(orderID)
+}
+
+func (s *FabricStore) PutOrder(orderID string, value []byte) error {
+	return s.put(orderID, value)
+}
+
+func (s *FabricStore) DeleteOrder(orderID string) error {
+	return s.delete(orderID)
+}
+
+func (s *FabricStore) PutBookEntry(key string, value []byte) error {
+	return s.put(key, value)
+}
+
+func (s *FabricStore) DeleteBookEntry(key string) error {
+	return s.delete(key)
+}
+
+func (s *FabricStore) ListBookEntriesByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *FabricStore) PutTrade(key string, value []byte) error {
+	return s.put(key, value)
+}
+
+func (s *FabricStore) HistoryOf(key string) ([]HistoricValue, error) {
+	iterator, err := s.stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for key: %v", err)
+	}
+	defer iterator.Close()
+
+	var history []HistoricValue
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over history: %v", err)
+		}
+		history = append(history, HistoricValue{
+			TxID:      modification.TxId,
+			Value:     modification.Value,
+			Timestamp: modification.Timestamp.AsTime(),
+			IsDelete:  modification.IsDelete,
+		})
+	}
+	return history, nil
+}
+
+func (s *FabricStore) EmitEvent(name string, payload []byte) error {
+	if err := s.stub.SetEvent(name, payload); err != nil {
+		return fmt.Errorf("failed to emit event: %v", err)
+	}
+	return nil
+}
+
+func (s *FabricStore) get(key string) ([]byte, error) {
+	value, err := s.stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from world state: %v", key, err)
+	}
+	return value, nil
+}
+
+func (s *FabricStore) put(key string, value []byte) error {
+	if err := s.stub.PutState(key, value); err != nil {
+		return fmt.Errorf("failed to put %s in world state: %v", key, err)
+	}
+	return nil
+}
+
+func (s *FabricStore) delete(key string) error {
+	if err := s.stub.DelState(key); err != nil {
+		return fmt.Errorf("failed to delete %s from world state: %v", key, err)
+	}
+	return nil
+}
+
+func (s *FabricStore) listByRange(startKey, endKey string) ([]KeyValue, error) {
+	iterator, err := s.stub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state by range: %v", err)
+	}
+	defer iterator.Close()
+
+	var results []KeyValue
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over results: %v", err)
+		}
+		results = append(results, KeyValue{Key: entry.Key, Value: entry.Value})
+	}
+	return results, nil
+}
+
+func (s *FabricStore) richQuery(selector string, pageSize int32, bookmark string) (QueryPage, error) {
+	iterator, metadata, err := s.stub.GetQueryResultWithPagination(selector, pageSize, bookmark)
+	if err != nil {
+		return QueryPage{}, fmt.Errorf("failed to run rich query: %v", err)
+	}
+	defer iterator.Close()
+
+	var results []KeyValue
+	for iterator.HasNext() {
+		entry, err := iterator.Next()
+		if err != nil {
+			return QueryPage{}, fmt.Errorf("failed to iterate over query results: %v", err)
+		}
+		results = append(results, KeyValue{Key: entry.Key, Value: entry.Value})
+	}
+	return QueryPage{
+		Results:        results,
+		Bookmark:       metadata.Bookmark,
+		FetchedRecords: metadata.FetchedRecordsCount,
+	}, nil
+}
@@ -0,0 +1,209 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryStore is a map-backed Store for unit tests. It keeps a synthetic
+// history list per key so HistoryOf behaves like GetHistoryForKey against a
+// real ledger, without needing a Fabric network.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	data    map[string][]byte
+	history map[string][]HistoricValue
+	events  []Event
+	nextTx  int
+	clock   func() time.Time
+}
+
+// Event is one chaincode event captured by InMemoryStore.EmitEvent.
+type Event struct {
+	Name    string
+	Payload []byte
+}
+
+// NewInMemoryStore creates an empty store. clock defaults to time.Now if
+// nil; tests that need deterministic timestamps can supply their own (see
+// the dummychain block clock).
+func NewInMemoryStore(clock func() time.Time) *InMemoryStore {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &InMemoryStore{
+		data:    make(map[string][]byte),
+		history: make(map[string][]HistoricValue),
+		clock:   clock,
+	}
+}
+
+func (s *InMemoryStore) GetTransaction(txID string) ([]byte, error) {
+	key, _ := s.TransactionKey(txID)
+	return s.get(key)
+}
+func (s *InMemoryStore) PutTransaction(txID string, value []byte) error {
+	key, _ := s.TransactionKey(txID)
+	return s.put(key, value)
+}
+
+// TransactionKey reproduces the composite-key format real Fabric's
+// CreateCompositeKey produces (see CompositeKey); InMemoryStore has no
+// real stub to call CreateCompositeKey against, so it never errors.
+func (s *InMemoryStore) TransactionKey(txID string) (string, error) {
+	return CompositeKey(transactionObjectType, txID), nil
+}
+
+func (s *InMemoryStore) QueryTransactions(selector string, pageSize int32, bookmark string) (QueryPage, error) {
+	return s.richQuery(selector, pageSize, bookmark)
+}
+
+func (s *InMemoryStore) GetAccount(address string) ([]byte, error) {
+	key, _ := s.AccountKey(address)
+	return s.get(key)
+}
+func (s *InMemoryStore) PutAccount(address string, value []byte) error {
+	key, _ := s.AccountKey(address)
+	return s.put(key, value)
+}
+
+// AccountKey is TransactionKey's counterpart for an account address.
+func (s *InMemoryStore) AccountKey(address string) (string, error) {
+	return CompositeKey(accountObjectType, address), nil
+}
+
+func (s *InMemoryStore) QueryAccounts(selector string, pageSize int32, bookmark string) (QueryPage, error) {
+	return s.richQuery(selector, pageSize, bookmark)
+}
+
+func (s *InMemoryStore) GetMedicine(id string) ([]byte, error) {
+	key, _ := s.MedicineKey(id)
+	return s.get(key)
+}
+func (s *InMemoryStore) PutMedicine(id string, value []byte) error {
+	key, _ := s.MedicineKey(id)
+	return s.put(key, value)
+}
+
+// MedicineKey reproduces the composite-key format real Fabric's
+// CreateCompositeKey produces (see CompositeKey); InMemoryStore has no real
+// stub to call CreateCompositeKey against, so it never errors.
+func (s *InMemoryStore) MedicineKey(id string) (string, error) {
+	return CompositeKey(medicineObjectType, id), nil
+}
+
+func (s *InMemoryStore) ListMedicinesByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *InMemoryStore) PutSensorReading(key string, value []byte) error {
+	return s.put(key, value)
+}
+
+func (s *InMemoryStore) ListSensorReadingsByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *InMemoryStore) GetOrder(orderID string) ([]byte, error) { return s.get(orderID) }
+func (s *InMemoryStore) PutOrder(orderID string, value []byte) error {
+	return s.put(orderID, value)
+}
+func (s *InMemoryStore) DeleteOrder(orderID string) error { return s.delete(orderID) }
+
+func (s *InMemoryStore) PutBookEntry(key string, value []byte) error {
+	return s.put(key, value)
+}
+func (s *InMemoryStore) DeleteBookEntry(key string) error { return s.delete(key) }
+func (s *InMemoryStore) ListBookEntriesByRange(startKey, endKey string) ([]KeyValue, error) {
+	return s.listByRange(startKey, endKey)
+}
+
+func (s *InMemoryStore) PutTrade(key string, value []byte) error {
+	return s.put(key, value)
+}
+
+func (s *InMemoryStore) HistoryOf(key string) ([]HistoricValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.history[key]
+	// Most recent first, matching GetHistoryForKey.
+	reversed := make([]HistoricValue, len(versions))
+	for i, v := range versions {
+		reversed[len(versions)-1-i] = v
+	}
+	return reversed, nil
+}
+
+func (s *InMemoryStore) EmitEvent(name string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, Event{Name: name, Payload: payload})
+	return nil
+}
+
+// Events returns every event emitted so far, for test assertions.
+func (s *InMemoryStore) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func (s *InMemoryStore) get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func (s *InMemoryStore) put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = value
+	s.nextTx++
+	s.history[key] = append(s.history[key], HistoricValue{
+		TxID:      fmt.Sprintf("TX%d", s.nextTx),
+		Value:     value,
+		Timestamp: s.clock(),
+		IsDelete:  false,
+	})
+	return nil
+}
+
+func (s *InMemoryStore) delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+	s.nextTx++
+	s.history[key] = append(s.history[key], HistoricValue{
+		TxID:      fmt.Sprintf("TX%d", s.nextTx),
+		Timestamp: s.clock(),
+		IsDelete:  true,
+	})
+	return nil
+}
+
+func (s *InMemoryStore) listByRange(startKey, endKey string) ([]KeyValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if startKey != "" && key < startKey {
+			continue
+		}
+		if endKey != "" && key >= endKey {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]KeyValue, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, KeyValue{Key: key, Value: s.data[key]})
+	}
+	return results, nil
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Deploying the indexes QueryTransactions/QueryAccounts selectors rely on:
+// index definitions live under
+// chaincode/medicine-tracking/META-INF/statedb/couchdb/indexes/ — inside
+// the medicine-tracking directory itself, the one with the package main
+// that actually gets built and packaged as the chaincode binary, not this
+// store package or the chaincode/ directory above it.
+// Fabric's lifecycle reads META-INF out of that packaged chaincode and
+// creates each index's CouchDB design document at instantiation/upgrade
+// time; no separate deploy step is needed beyond packaging META-INF
+// alongside the chaincode's Go sources. FabricStore's richQuery hands the
+// query to the stub, so on a real peer it runs against CouchDB and these
+// indexes. InMemoryStore's richQuery and dummychain's fakeStub don't read
+// these files at all — they evaluate the selector in Go, so tests pass
+// whether or not an index actually exists for it.
+
+// ParseSelector extracts the "selector" clause from a CouchDB Mango query
+// document (the format GetQueryResultWithPagination's query argument
+// takes), for callers emulating rich queries without a live CouchDB (this
+// file's InMemoryStore.richQuery, and the dummychain test harness's fake
+// stub).
+func ParseSelector(query string) (map[string]interface{}, error) {
+	var parsed struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	if err := json.Unmarshal([]byte(query), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal selector: %v", err)
+	}
+	return parsed.Selector, nil
+}
+
+// MatchSelector reports whether doc satisfies every field condition in
+// selector (as returned by ParseSelector). It understands equality and the
+// $eq/$ne/$gt/$gte/$lt/$lte comparison operators; compound $and/$or clauses
+// are not supported, which is enough for the indexed selectors this repo
+// ships (see META-INF/statedb/couchdb/indexes).
+func MatchSelector(selector map[string]interface{}, doc map[string]interface{}) bool {
+	for field, condition := range selector {
+		if !matchesField(condition, doc[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesField(condition, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	ops, isOps := condition.(map[string]interface{})
+	if !isOps {
+		return compareValues(value, condition) == 0
+	}
+	for op, want := range ops {
+		cmp := compareValues(value, want)
+		switch op {
+		case "$eq":
+			if cmp != 0 {
+				return false
+			}
+		case "$ne":
+			if cmp == 0 {
+				return false
+			}
+		case "$gt":
+			if cmp <= 0 {
+				return false
+			}
+		case "$gte":
+			if cmp < 0 {
+				return false
+			}
+		case "$lt":
+			if cmp >= 0 {
+				return false
+			}
+		case "$lte":
+			if cmp > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// compareValues orders two decoded-JSON values (float64 or string; a
+// time.Time field compares as its RFC 3339 encoding, which sorts
+// chronologically). Values of different or unsupported types compare
+// equal rather than panicking: against such a value a plain equality,
+// $eq, $gte or $lte condition always holds, and a $ne, $gt or $lt
+// condition never does.
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// richQuery emulates GetQueryResultWithPagination against InMemoryStore's
+// map: every JSON-object value is evaluated against selectorJSON's selector
+// clause, in key order, returning at most pageSize matches starting after
+// bookmark (the key the previous page ended on).
+func (s *InMemoryStore) richQuery(selectorJSON string, pageSize int32, bookmark string) (QueryPage, error) {
+	selector, err := ParseSelector(selectorJSON)
+	if err != nil {
+		return QueryPage{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if bookmark != "" {
+		start = sort.SearchStrings(keys, bookmark)
+		if start < len(keys) && keys[start] == bookmark {
+			start++
+		}
+	}
+
+	var page QueryPage
+	for _, key := range keys[start:] {
+		if pageSize > 0 && page.FetchedRecords >= pageSize {
+			break
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(s.data[key], &doc); err != nil {
+			continue
+		}
+		if !MatchSelector(selector, doc) {
+			continue
+		}
+		page.Results = append(page.Results, KeyValue{Key: key, Value: s.data[key]})
+		page.FetchedRecords++
+		page.Bookmark = key
+	}
+	return page, nil
+}
@@ -0,0 +1,166 @@
+// Package store decouples the chaincode's business logic from
+// ctx.GetStub(), so TransactionContract and MedicineTrackingContract can be
+// exercised against an in-memory world state in unit tests and against
+// other backends (BadgerDB, CouchDB rich-query) later without touching
+// contract code.
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// minUnicodeRuneValue is the namespace byte real Fabric's
+// ChaincodeStubInterface.CreateCompositeKey prefixes a composite key with,
+// and the separator it places after the object type and after every
+// attribute. Fabric picks U+0000 specifically: it sorts before any
+// printable key and CreateCompositeKey itself rejects the byte inside an
+// attribute, so it can never collide with attribute content.
+const minUnicodeRuneValue = "\x00"
+
+// CompositeKey reproduces the byte format real Fabric's
+// ChaincodeStubInterface.CreateCompositeKey produces, for InMemoryStore
+// and the dummychain test harness to use, neither of which has a real
+// stub to call CreateCompositeKey against. FabricStore does not use this:
+// it calls ctx.GetStub().CreateCompositeKey directly (see
+// FabricStore.TransactionKey/AccountKey), so production keys go through
+// Fabric's own attribute validation instead of this hand-rolled copy.
+// Called with fewer attributes than a full key, CompositeKey doubles as
+// the partial-key prefix GetStateByPartialCompositeKey matches on.
+func CompositeKey(objectType string, attributes ...string) string {
+	var b strings.Builder
+	b.WriteString(minUnicodeRuneValue)
+	b.WriteString(objectType)
+	b.WriteString(minUnicodeRuneValue)
+	for _, attr := range attributes {
+		b.WriteString(attr)
+		b.WriteString(minUnicodeRuneValue)
+	}
+	return b.String()
+}
+
+// transactionObjectType and accountObjectType are the composite-key object
+// types TransactionKey and AccountKey index under; they double as the
+// "docType" field value TransactionContract stamps on the JSON documents
+// themselves, which is what the CouchDB selectors in
+// META-INF/statedb/couchdb/indexes actually filter on. medicineObjectType
+// is MedicineKey's counterpart: MedicineTrackingContract has no CouchDB
+// rich-query path of its own, so it namespaces medicine keys purely to
+// keep ListMedicinesByRange from scanning the other contracts' keys in
+// this same chaincode's shared world state (see MedicineKeyRange).
+const (
+	transactionObjectType = "transaction"
+	accountObjectType     = "account"
+	medicineObjectType    = "medicine"
+)
+
+// MedicineKeyRange returns the [start, end) range covering every Medicine
+// composite key, the same "~" upper-bound technique
+// medicine-tracking/excursions.go's sensorKeyRange and
+// exchange_contract.go's oppositeBookRange use ("~" sorts after any digit
+// or letter a medicine ID would contain). Without this, ListMedicinesByRange
+// would have to scan every key in the chaincode's shared world state —
+// every account, order, trade and sensor reading ExchangeContract and the
+// excursion subsystem write, not just medicines.
+func MedicineKeyRange() (startKey, endKey string) {
+	prefix := CompositeKey(medicineObjectType)
+	return prefix, prefix + "~"
+}
+
+// KeyValue is one entry returned by a range or rich query.
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+// QueryPage is one page of a paginated rich query, mirroring the
+// (results, metadata) pair GetQueryResultWithPagination returns.
+type QueryPage struct {
+	Results        []KeyValue
+	Bookmark       string
+	FetchedRecords int32
+}
+
+// HistoricValue is one version of a key returned by HistoryOf, ordered most
+// recent first to match ChaincodeStubInterface.GetHistoryForKey.
+type HistoricValue struct {
+	TxID      string
+	Value     []byte
+	Timestamp time.Time
+	IsDelete  bool
+}
+
+// Store is the world-state surface contracts are written against. Every
+// method that in Fabric would touch ctx.GetStub() instead goes through
+// here, so FabricStore and InMemoryStore are interchangeable behind it.
+type Store interface {
+	GetTransaction(txID string) ([]byte, error)
+	PutTransaction(txID string, value []byte) error
+
+	// TransactionKey returns the key a transaction is stored under, for
+	// external callers (TransactionContract.GetTransactionHistory) that
+	// need the exact same key PutTransaction used for a raw HistoryOf
+	// lookup. FabricStore builds it via ctx.GetStub().CreateCompositeKey;
+	// InMemoryStore reproduces that format itself (see CompositeKey).
+	TransactionKey(txID string) (string, error)
+
+	// QueryTransactions runs a CouchDB Mango query (the same "query"
+	// argument GetQueryResultWithPagination takes, e.g.
+	// `{"selector":{"docType":"transaction","sender":"addr1"}}`) against
+	// transaction documents and returns one page of matches. pageSize
+	// bounds the page; bookmark resumes after a previous page ("" for the
+	// first page).
+	QueryTransactions(selector string, pageSize int32, bookmark string) (QueryPage, error)
+
+	GetAccount(address string) ([]byte, error)
+	PutAccount(address string, value []byte) error
+
+	// AccountKey is TransactionKey's counterpart for an account address.
+	AccountKey(address string) (string, error)
+
+	// QueryAccounts is QueryTransactions' counterpart over account
+	// documents.
+	QueryAccounts(selector string, pageSize int32, bookmark string) (QueryPage, error)
+
+	GetMedicine(id string) ([]byte, error)
+	PutMedicine(id string, value []byte) error
+
+	// MedicineKey is TransactionKey/AccountKey's counterpart for a
+	// medicine ID, for external callers (MedicineTrackingContract.
+	// GetMedicineHistory) that need the exact same key PutMedicine used
+	// for a raw HistoryOf lookup.
+	MedicineKey(id string) (string, error)
+
+	// ListMedicinesByRange expects the bounds from MedicineKeyRange, not
+	// an unbounded ("", "") scan: this chaincode's contracts all share one
+	// world state, so an unbounded range would also return every account,
+	// order, trade and sensor reading in the ledger.
+	ListMedicinesByRange(startKey, endKey string) ([]KeyValue, error)
+
+	// PutSensorReading records one IoT gateway reading under key (by
+	// convention "SENSOR_<medicineID>_<unixNano>").
+	PutSensorReading(key string, value []byte) error
+	// ListSensorReadingsByRange scans sensor readings in key order, so
+	// callers can range over every reading for a medicine ID.
+	ListSensorReadingsByRange(startKey, endKey string) ([]KeyValue, error)
+
+	GetOrder(orderID string) ([]byte, error)
+	PutOrder(orderID string, value []byte) error
+	DeleteOrder(orderID string) error
+
+	// PutBookEntry and DeleteBookEntry maintain the BID_/ASK_ composite-key
+	// indexes ExchangeContract scans for price-time priority matching.
+	PutBookEntry(key string, value []byte) error
+	DeleteBookEntry(key string) error
+	ListBookEntriesByRange(startKey, endKey string) ([]KeyValue, error)
+
+	// PutTrade records a settled match under key (by convention
+	// "TRADE_<orderID>"); GetTradeHistory reads it back via HistoryOf.
+	PutTrade(key string, value []byte) error
+
+	// HistoryOf returns every recorded version of key, most recent first.
+	HistoryOf(key string) ([]HistoricValue, error)
+
+	// EmitEvent emits a chaincode event carrying payload under name.
+	EmitEvent(name string, payload []byte) error
+}